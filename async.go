@@ -0,0 +1,240 @@
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy decides what an AsyncWriter does with a write when its queue
+// is full.
+type OverflowPolicy int
+
+const (
+	// Block makes the write wait until there is room in the queue.
+	Block OverflowPolicy = iota
+
+	// DropNewest drops the message being written.
+	DropNewest
+
+	// DropOldest drops the oldest queued message to make room.
+	DropOldest
+)
+
+// AsyncOptions configures NewAsyncWriter.
+type AsyncOptions struct {
+	// QueueSize is the maximum number of messages waiting to be written. It
+	// defaults to 1024.
+	QueueSize int
+
+	// Overflow is what happens to writes when the queue is full. It
+	// defaults to Block.
+	Overflow OverflowPolicy
+
+	// MarkerInterval is how often a line reporting the number of messages
+	// dropped since the last one is written, if any were. It is measured
+	// with the Clock of the Logger and defaults to one minute.
+	MarkerInterval time.Duration
+
+	// CloseTimeout is how long Close waits for the queue to drain. It
+	// defaults to 5 seconds.
+	CloseTimeout time.Duration
+
+	// OnError, if set, is called with the errors of the writes to the
+	// Logger, which happen after the Write of the AsyncWriter returned.
+	OnError func(error)
+}
+
+// errAsyncClosed is returned by writes to a closed AsyncWriter.
+var errAsyncClosed = errors.New("write to closed async writer")
+
+// AsyncWriter writes to a Logger from a single background goroutine, so that
+// callers are not held up by disk I/O or rotations. Messages are kept in a
+// bounded queue in the meantime; what happens when it is full is set by the
+// OverflowPolicy. It is safe for concurrent use.
+type AsyncWriter struct {
+	l    *Logger
+	opts AsyncOptions
+
+	mu      sync.Mutex
+	notFull *sync.Cond
+	// queue is a ring buffer of n messages starting at head.
+	queue  [][]byte
+	head   int
+	n      int
+	closed bool
+
+	dropped atomic.Uint64
+	// unreported is the number of messages dropped since the last marker.
+	unreported atomic.Uint64
+	aborted    atomic.Bool
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+// NewAsyncWriter returns an AsyncWriter writing to l and starts its
+// goroutine. opts may be nil. The AsyncWriter must be closed to stop the
+// goroutine.
+func NewAsyncWriter(l *Logger, opts *AsyncOptions) *AsyncWriter {
+	w := &AsyncWriter{
+		l:    l,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.QueueSize <= 0 {
+		w.opts.QueueSize = 1024
+	}
+	if w.opts.MarkerInterval <= 0 {
+		w.opts.MarkerInterval = time.Minute
+	}
+	if w.opts.CloseTimeout <= 0 {
+		w.opts.CloseTimeout = 5 * time.Second
+	}
+	w.queue = make([][]byte, w.opts.QueueSize)
+	w.notFull = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p to be written to the Logger. It only blocks when
+// the queue is full and the OverflowPolicy is Block. Dropped messages are
+// reported as written.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	msg := append([]byte(nil), p...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.opts.Overflow == Block {
+		for w.n == len(w.queue) && !w.closed {
+			w.notFull.Wait()
+		}
+	}
+	if w.closed {
+		return 0, errAsyncClosed
+	}
+	if w.n == len(w.queue) {
+		w.drop(1)
+		if w.opts.Overflow == DropNewest {
+			return len(p), nil
+		}
+		w.queue[w.head] = nil
+		w.head = (w.head + 1) % len(w.queue)
+		w.n--
+	}
+	w.queue[(w.head+w.n)%len(w.queue)] = msg
+	w.n++
+	w.signal()
+	return len(p), nil
+}
+
+// Dropped returns the number of messages dropped so far.
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close stops accepting writes and waits up to CloseTimeout for the queued
+// messages to be written. Messages still queued after that are dropped. It
+// does not close the Logger.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.notFull.Broadcast()
+	w.mu.Unlock()
+	w.signal()
+
+	timer := time.NewTimer(w.opts.CloseTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+		return nil
+	case <-timer.C:
+	}
+	w.aborted.Store(true)
+	w.mu.Lock()
+	n := w.n
+	w.mu.Unlock()
+	return fmt.Errorf("can't drain async writer: %d messages still queued after %s", n, w.opts.CloseTimeout)
+}
+
+// drop counts n dropped messages.
+func (w *AsyncWriter) drop(n int) {
+	w.dropped.Add(uint64(n))
+	w.unreported.Add(uint64(n))
+}
+
+// signal wakes the goroutine up without blocking.
+func (w *AsyncWriter) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take removes and returns all the queued messages, and whether the
+// AsyncWriter is closed.
+func (w *AsyncWriter) take() ([][]byte, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	msgs := make([][]byte, 0, w.n)
+	for ; w.n > 0; w.n-- {
+		msgs = append(msgs, w.queue[w.head])
+		w.queue[w.head] = nil
+		w.head = (w.head + 1) % len(w.queue)
+	}
+	w.notFull.Broadcast()
+	return msgs, w.closed
+}
+
+// run writes the queued messages to the Logger until the AsyncWriter is
+// closed and drained.
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	ticker := w.l.clock().NewTicker(w.opts.MarkerInterval)
+	defer ticker.Stop()
+
+	for {
+		msgs, closed := w.take()
+		if len(msgs) > 0 {
+			if w.aborted.Load() {
+				w.drop(len(msgs))
+				return
+			}
+			// The queued messages are written together. Those that could
+			// not be written are dropped.
+			_, lost, err := w.l.writeBatch(msgs)
+			if lost > 0 {
+				w.drop(lost)
+			}
+			report(w.opts.OnError, err)
+			continue
+		}
+		if closed {
+			w.writeMarker()
+			return
+		}
+		select {
+		case <-w.wake:
+		case <-ticker.C():
+			w.writeMarker()
+		}
+	}
+}
+
+// writeMarker writes a line with the number of messages dropped since the
+// last one, if any were.
+func (w *AsyncWriter) writeMarker() {
+	if n := w.unreported.Swap(0); n > 0 {
+		_, err := fmt.Fprintf(w.l, "logrotate: %d messages dropped\n", n)
+		report(w.opts.OnError, err)
+	}
+}
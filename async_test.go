@@ -0,0 +1,154 @@
+package logrotate
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockedFS returns a MemFS whose first write blocks until release is
+// closed. started is closed once that write has begun.
+func blockedFS() (fsys *MemFS, started, release chan struct{}) {
+	fsys = NewMemFS()
+	started = make(chan struct{})
+	release = make(chan struct{})
+	var once sync.Once
+	fsys.Fail = func(op, name string) error {
+		if op == "write" {
+			once.Do(func() {
+				close(started)
+				<-release
+			})
+		}
+		return nil
+	}
+	return fsys, started, release
+}
+
+func TestAsyncWriter(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	l := &Logger{Filename: logFile("/logs"), FS: fsys}
+	defer l.Close()
+	w := NewAsyncWriter(l, nil)
+
+	var exp []byte
+	for i := 0; i < 100; i++ {
+		msg := []byte(fmt.Sprintf("line %d\n", i))
+		n, err := w.Write(msg)
+		isNil(err, t)
+		equals(len(msg), n, t)
+		exp = append(exp, msg...)
+	}
+	isNil(w.Close(), t)
+	equals(uint64(0), w.Dropped(), t)
+	memExistsWithContent(fsys, logFile("/logs"), exp, t)
+
+	_, err := w.Write([]byte("boo!"))
+	notNil(err, t)
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	currentTime = fakeTime
+	tests := []struct {
+		policy OverflowPolicy
+		exp    string
+	}{
+		{DropNewest, "1\n2\n3\nlogrotate: 1 messages dropped\n"},
+		{DropOldest, "1\n3\n4\nlogrotate: 1 messages dropped\n"},
+	}
+	for _, tt := range tests {
+		fsys, started, release := blockedFS()
+		l := &Logger{Filename: logFile("/logs"), FS: fsys}
+		w := NewAsyncWriter(l, &AsyncOptions{QueueSize: 2, Overflow: tt.policy})
+
+		_, err := w.Write([]byte("1\n"))
+		isNil(err, t)
+		<-started
+		// the first message is being written, the next two fill the
+		// queue, and the last one overflows it.
+		for _, s := range []string{"2\n", "3\n", "4\n"} {
+			_, err := w.Write([]byte(s))
+			isNil(err, t)
+		}
+		equals(uint64(1), w.Dropped(), t)
+
+		close(release)
+		isNil(w.Close(), t)
+		memExistsWithContent(fsys, logFile("/logs"), []byte(tt.exp), t)
+		isNil(l.Close(), t)
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	currentTime = fakeTime
+	fsys, started, release := blockedFS()
+	l := &Logger{Filename: logFile("/logs"), FS: fsys}
+	defer l.Close()
+	w := NewAsyncWriter(l, &AsyncOptions{QueueSize: 1, Overflow: Block})
+
+	_, err := w.Write([]byte("1\n"))
+	isNil(err, t)
+	<-started
+	_, err = w.Write([]byte("2\n"))
+	isNil(err, t)
+
+	done := make(chan error)
+	go func() {
+		_, err := w.Write([]byte("3\n"))
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected the write to block while the queue is full")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	isNil(<-done, t)
+	isNil(w.Close(), t)
+	equals(uint64(0), w.Dropped(), t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("1\n2\n3\n"), t)
+}
+
+func TestAsyncWriterCloseTimeout(t *testing.T) {
+	currentTime = fakeTime
+	fsys, started, release := blockedFS()
+	l := &Logger{Filename: logFile("/logs"), FS: fsys}
+	defer l.Close()
+	w := NewAsyncWriter(l, &AsyncOptions{CloseTimeout: 10 * time.Millisecond})
+
+	_, err := w.Write([]byte("1\n"))
+	isNil(err, t)
+	<-started
+	_, err = w.Write([]byte("2\n"))
+	isNil(err, t)
+
+	notNil(w.Close(), t)
+	close(release)
+	<-w.done
+	memExistsWithContent(fsys, logFile("/logs"), []byte("1\n"), t)
+	equals(uint64(1), w.Dropped(), t)
+}
+
+func TestAsyncWriterTooLong(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	l := &Logger{Filename: logFile("/logs"), MaxBytes: 40, FS: fsys}
+	defer l.Close()
+	var errs []error
+	w := NewAsyncWriter(l, &AsyncOptions{OnError: func(err error) { errs = append(errs, err) }})
+
+	for _, msg := range []string{"ok1", "this message is longer than forty bytes!!", "ok2"} {
+		_, err := w.Write([]byte(msg))
+		isNil(err, t)
+	}
+	isNil(w.Close(), t)
+
+	// the other queued messages are still written, and the one left out is
+	// counted as dropped.
+	equals(uint64(1), w.Dropped(), t)
+	assert(len(errs) > 0, t, "expected the error to be reported")
+	memExistsWithContent(fsys, logFile("/logs"), []byte("ok1ok2logrotate: 1 messages dropped\n"), t)
+}
@@ -0,0 +1,134 @@
+package logrotate
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+// BackupInfo describes a backup log file.
+type BackupInfo struct {
+	// Name is the base name of the backup file.
+	Name string
+
+	// Path is the path of the backup file.
+	Path string
+
+	// Time is the time the backup is sorted and expired by: the timestamp in
+	// its name with a FilenameTimeFormat, and its birth time otherwise.
+	Time time.Time
+
+	// Size is the size of the backup file in bytes.
+	Size int64
+
+	// Compressed reports whether the backup file is compressed.
+	Compressed bool
+
+	// Encrypted reports whether the backup file is encrypted.
+	Encrypted bool
+
+	// Order is the order in the name of backups with the standard file name
+	// format. It is 0 with a FilenameTimeFormat.
+	Order int
+
+	// Pinned reports whether the backup is pinned. It is only set by
+	// Logger.Backups.
+	Pinned bool
+}
+
+// Plan lists what the next compression, encryption and removal of old log
+// files would do, as returned by Logger.Plan.
+type Plan struct {
+	Compress []BackupInfo
+	Encrypt  []BackupInfo
+	Remove   []BackupInfo
+}
+
+// Backups returns the backup log files of the Logger, newest first. They are
+// discovered the same way as when old log files are cleaned up.
+func (l *Logger) Backups() ([]BackupInfo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.dirExists() {
+		return nil, nil
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return nil, err
+	}
+	pins, err := l.loadPins()
+	if err != nil {
+		return nil, err
+	}
+	infos := l.backupInfos(files)
+	for i := range infos {
+		infos[i].Pinned = pins[baseBackupName(infos[i].Name)]
+	}
+	return infos, nil
+}
+
+// Plan returns the backups that would be compressed, encrypted and removed
+// if old log files were cleaned up now, without doing it.
+func (l *Logger) Plan() (Plan, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.dirExists() {
+		return Plan{}, nil
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return Plan{}, err
+	}
+	compress, encrypt, remove, err := l.millPlan(files)
+	if err != nil {
+		return Plan{}, err
+	}
+	return Plan{
+		Compress: l.backupInfos(compress),
+		Encrypt:  l.backupInfos(encrypt),
+		Remove:   l.backupInfos(remove),
+	}, nil
+}
+
+// OpenBackup returns a reader over the original content of the backup named
+// name, as listed by Backups, decrypting and decompressing it as needed.
+func (l *Logger) OpenBackup(name string) (io.ReadCloser, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	backup, err := l.isBackupName(name)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Base(name) != name || !backup {
+		return nil, fmt.Errorf("%s is not a backup of %s", name, l.filename())
+	}
+	path := filepath.Join(l.dir(), name)
+	if _, err := l.fs().Stat(path); err != nil {
+		return nil, fmt.Errorf("can't open log file: %s", err)
+	}
+	return l.openSegments([]segment{{path: path, backup: true}}), nil
+}
+
+// backupInfos converts old log files to BackupInfos.
+func (l *Logger) backupInfos(files []logInfo) []BackupInfo {
+	var infos []BackupInfo
+	for _, f := range files {
+		info := BackupInfo{
+			Name:       f.Name(),
+			Path:       filepath.Join(l.dir(), f.Name()),
+			Time:       f.timestamp,
+			Size:       f.Size(),
+			Compressed: isCompressed(f.Name()),
+			Encrypted:  isEncrypted(f.Name()),
+		}
+		if l.FilenameTimeFormat == "" {
+			info.Order = l.backupOrder(f.Name())
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
@@ -0,0 +1,102 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackups(t *testing.T) {
+	currentTime = time.Now
+	dir := makeTempDir("TestBackups", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+	}
+	defer l.Close()
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(0, len(backups), t)
+
+	for _, s := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+	}
+
+	backups, err = l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	for _, b := range backups {
+		equals(true, b.Compressed, t)
+		equals(filepath.Join(dir, b.Name), b.Path, t)
+		equals(filepath.Base(backupFileWithOrder(dir, b.Order))+compressSuffix, b.Name, t)
+		assert(!b.Time.IsZero(), t, "expected a backup time")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestPlan", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:           logFile(dir),
+		FilenameTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+
+	names := []string{}
+	for i := 0; i < 3; i++ {
+		_, err := l.Write([]byte("boo!"))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+		names = append(names, filepath.Base(backupFileWithTime(dir, backupTimeFormat)))
+	}
+
+	l.MaxBackups = 1
+	l.Compress = true
+	plan, err := l.Plan()
+	isNil(err, t)
+
+	// Nothing happened yet.
+	fileCount(dir, 4, t)
+	equals(1, len(plan.Compress), t)
+	equals(names[2], plan.Compress[0].Name, t)
+	equals(2, len(plan.Remove), t)
+	equals(names[1], plan.Remove[0].Name, t)
+	equals(names[0], plan.Remove[1].Name, t)
+}
+
+func TestOpenBackup(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestOpenBackup", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+
+	r, err := l.OpenBackup(filepath.Base(backupFileWithOrder(dir, 1)) + compressSuffix)
+	isNil(err, t)
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	isNil(err, t)
+	equals(b, content, t)
+
+	_, err = l.OpenBackup(filepath.Base(logFile(dir)))
+	notNil(err, t)
+}
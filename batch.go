@@ -0,0 +1,164 @@
+package logrotate
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	// maxBatchBuffers is the maximum number of buffers written by one
+	// vectored write, the usual IOV_MAX.
+	maxBatchBuffers = 1024
+
+	// coalesceLimit is the size under which messages are copied together
+	// instead of getting a buffer of their own in a vectored write, which
+	// is slower for many small buffers than copying them.
+	coalesceLimit = 4096
+
+	// maxScratch is the largest scratch buffer kept between batches.
+	maxScratch = 256 * 1024
+)
+
+// WriteBatch writes msgs to the log file as Write would write them one after
+// the other, but with as few system calls as possible: on Linux, consecutive
+// messages are written together with writev. Messages are never split across
+// files, so the file is rotated before the first message that would make it
+// larger than MaxBytes, exactly as with Write. Messages larger than MaxBytes
+// are left out and reported by the error, while the others are still
+// written. It returns the number of bytes written.
+func (l *Logger) WriteBatch(msgs [][]byte) (n int, err error) {
+	n, _, err = l.writeBatch(msgs)
+	return n, err
+}
+
+// writeBatch is WriteBatch, also returning the number of messages that were
+// not written in full.
+func (l *Logger) writeBatch(msgs [][]byte) (n, lost int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// kept is only allocated once a message is left out.
+	var kept [][]byte
+	for i, p := range msgs {
+		writeLen := int64(len(p))
+		if writeLen <= l.max(writeLen) {
+			if kept != nil {
+				kept = append(kept, p)
+			}
+			continue
+		}
+		if kept == nil {
+			kept = append(make([][]byte, 0, len(msgs)), msgs[:i]...)
+		}
+		lost++
+		if err == nil {
+			err = fmt.Errorf(
+				"write length %d exceeds maximum file size %d", writeLen, l.max(writeLen),
+			)
+		}
+	}
+	if kept != nil {
+		msgs = kept
+	}
+	if len(msgs) == 0 {
+		return 0, lost, err
+	}
+
+	if l.file == nil {
+		if errOpen := l.openExistingOrNew(); errOpen != nil {
+			return 0, lost + len(msgs), errOpen
+		}
+	}
+
+	for len(msgs) > 0 {
+		// Take the messages that fit in the current file.
+		var batchLen int64
+		i := 0
+		for ; i < len(msgs); i++ {
+			writeLen := int64(len(msgs[i]))
+			if l.size+batchLen+writeLen > l.max(batchLen+writeLen) {
+				break
+			}
+			batchLen += writeLen
+		}
+		if i == 0 || l.stale() {
+			if errRotate := l.rotate(); errRotate != nil {
+				return n, lost + len(msgs), errRotate
+			}
+			continue
+		}
+
+		written, errWrite := writeBuffers(l.file, l.coalesce(msgs[:i]))
+		l.size += written
+		n += int(written)
+		l.signalFollowers()
+		if errWrite != nil {
+			// Count the messages written in full.
+			for len(msgs) > 0 && written >= int64(len(msgs[0])) {
+				written -= int64(len(msgs[0]))
+				msgs = msgs[1:]
+			}
+			return n, lost + len(msgs), errWrite
+		}
+		msgs = msgs[i:]
+	}
+	return n, lost, err
+}
+
+// coalesce returns bufs with consecutive small buffers copied together into
+// a scratch buffer of the Logger. bufs itself is left untouched.
+func (l *Logger) coalesce(bufs [][]byte) [][]byte {
+	small := 0
+	for _, b := range bufs {
+		if len(b) < coalesceLimit {
+			small += len(b)
+		}
+	}
+	// The scratch buffer must not grow while it is being filled, as the
+	// returned buffers point into it.
+	scratch := l.scratch[:0]
+	if cap(scratch) < small {
+		scratch = make([]byte, 0, small)
+		if small <= maxScratch {
+			l.scratch = scratch
+		}
+	}
+
+	var out [][]byte
+	start := 0
+	for _, b := range bufs {
+		if len(b) < coalesceLimit {
+			scratch = append(scratch, b...)
+			continue
+		}
+		if len(scratch) > start {
+			out = append(out, scratch[start:])
+			start = len(scratch)
+		}
+		out = append(out, b)
+	}
+	if len(scratch) > start {
+		out = append(out, scratch[start:])
+	}
+	return out
+}
+
+// writeBuffersEach writes bufs to f one buffer at a time, for files that
+// can't do vectored writes.
+func writeBuffersEach(f File, bufs [][]byte) (int64, error) {
+	b := net.Buffers(bufs)
+	return b.WriteTo(f)
+}
+
+// consumeBuffers removes the first n bytes from bufs.
+func consumeBuffers(bufs [][]byte, n int64) [][]byte {
+	for len(bufs) > 0 {
+		if n < int64(len(bufs[0])) {
+			bufs[0] = bufs[0][n:]
+			return bufs
+		}
+		n -= int64(len(bufs[0]))
+		bufs = bufs[1:]
+	}
+	return bufs
+}
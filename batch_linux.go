@@ -0,0 +1,62 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// writeBuffers writes bufs to f with vectored writes if f is an *os.File.
+// It consumes bufs.
+func writeBuffers(f File, bufs [][]byte) (int64, error) {
+	osf, ok := f.(*os.File)
+	if !ok {
+		return writeBuffersEach(f, bufs)
+	}
+	rc, err := osf.SyscallConn()
+	if err != nil {
+		return writeBuffersEach(f, bufs)
+	}
+
+	// Empty buffers would make a write of 0 bytes look like a short write.
+	nonEmpty := bufs[:0]
+	for _, b := range bufs {
+		if len(b) > 0 {
+			nonEmpty = append(nonEmpty, b)
+		}
+	}
+	bufs = nonEmpty
+
+	var n int64
+	var errWrite error
+	err = rc.Write(func(fd uintptr) bool {
+		for len(bufs) > 0 {
+			iov := bufs
+			if len(iov) > maxBatchBuffers {
+				iov = iov[:maxBatchBuffers]
+			}
+			written, err := unix.Writev(int(fd), iov)
+			if written > 0 {
+				n += int64(written)
+				bufs = consumeBuffers(bufs, int64(written))
+			}
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil {
+				errWrite = &os.PathError{Op: "writev", Path: osf.Name(), Err: err}
+				break
+			}
+			if written == 0 {
+				errWrite = io.ErrShortWrite
+				break
+			}
+		}
+		return true
+	})
+	if errWrite == nil {
+		errWrite = err
+	}
+	return n, errWrite
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package logrotate
+
+// writeBuffers writes bufs to f. It consumes bufs.
+func writeBuffers(f File, bufs [][]byte) (int64, error) {
+	return writeBuffersEach(f, bufs)
+}
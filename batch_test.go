@@ -0,0 +1,136 @@
+package logrotate
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestWriteBatch(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestWriteBatch", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		MaxBytes: 10,
+	}
+	defer l.Close()
+
+	msgs := [][]byte{[]byte("one!"), []byte("two!"), {}, []byte("three!"), []byte("four!")}
+	n, err := l.WriteBatch(msgs)
+	isNil(err, t)
+	equals(19, n, t)
+	equals([]byte("one!"), msgs[0], t)
+
+	// the batch is split where Write would have rotated.
+	existsWithContent(backupFileWithOrder(dir, 1), []byte("one!two!"), t)
+	existsWithContent(backupFileWithOrder(dir, 2), []byte("three!"), t)
+	existsWithContent(logFile(dir), []byte("four!"), t)
+	fileCount(dir, 3, t)
+
+	// only the message that is too long is left out.
+	n, err = l.WriteBatch([][]byte{[]byte("five!"), []byte("this is too long"), []byte("six!")})
+	notNil(err, t)
+	equals(9, n, t)
+	existsWithContent(backupFileWithOrder(dir, 3), []byte("four!five!"), t)
+	existsWithContent(logFile(dir), []byte("six!"), t)
+}
+
+func TestWriteBatchTooLong(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	l := &Logger{
+		Filename: logFile("/logs"),
+		MaxBytes: 10,
+		FS:       fsys,
+	}
+	defer l.Close()
+
+	n, lost, err := l.writeBatch([][]byte{[]byte("ok1"), bytes.Repeat([]byte("x"), 21), []byte("ok2")})
+	notNil(err, t)
+	equals(6, n, t)
+	equals(1, lost, t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("ok1ok2"), t)
+
+	_, lost, err = l.writeBatch([][]byte{bytes.Repeat([]byte("x"), 21)})
+	notNil(err, t)
+	equals(1, lost, t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("ok1ok2"), t)
+}
+
+func TestWriteBatchUnlimited(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	l := &Logger{
+		Filename: logFile("/logs"),
+		MaxBytes: -1,
+		FS:       fsys,
+	}
+	defer l.Close()
+
+	msgs := [][]byte{bytes.Repeat([]byte("x"), coalesceLimit), []byte("one!"), []byte("two!")}
+	_, err := l.WriteBatch(msgs)
+	isNil(err, t)
+	memExistsWithContent(fsys, logFile("/logs"), bytes.Join(msgs, nil), t)
+	memFileCount(fsys, "/logs", 1, t)
+}
+
+func TestWriteBatchMemFS(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	l := &Logger{
+		Filename: logFile("/logs"),
+		MaxBytes: 10,
+		FS:       fsys,
+	}
+	defer l.Close()
+
+	_, err := l.WriteBatch([][]byte{[]byte("one!"), []byte("two!"), []byte("three!")})
+	isNil(err, t)
+	memExistsWithContent(fsys, backupFileWithOrder("/logs", 1), []byte("one!two!"), t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("three!"), t)
+}
+
+func benchmarkMessages(n int) [][]byte {
+	msgs := make([][]byte, n)
+	for i := range msgs {
+		msgs[i] = bytes.Repeat([]byte("x"), 99)
+		msgs[i] = append(msgs[i], '\n')
+	}
+	return msgs
+}
+
+func BenchmarkWrite(b *testing.B) {
+	dir := makeTempDir("BenchmarkWrite", b)
+	defer os.RemoveAll(dir)
+	l := &Logger{Filename: logFile(dir), MaxBytes: -1}
+	defer l.Close()
+
+	msgs := benchmarkMessages(64)
+	b.SetBytes(64 * 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, msg := range msgs {
+			if _, err := l.Write(msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkWriteBatch(b *testing.B) {
+	dir := makeTempDir("BenchmarkWriteBatch", b)
+	defer os.RemoveAll(dir)
+	l := &Logger{Filename: logFile(dir), MaxBytes: -1}
+	defer l.Close()
+
+	msgs := benchmarkMessages(64)
+	b.SetBytes(64 * 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := l.WriteBatch(msgs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
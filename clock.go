@@ -0,0 +1,62 @@
+package logrotate
+
+import (
+	"time"
+)
+
+// Clock is the source of time of a Logger: it names backups, sets their
+// rotation time and decides when they expire, and schedules the periodic
+// work of the Logger. The logrotatetest package provides a fake one.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTicker returns a Ticker sending the time every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks of a Clock, like time.Ticker.
+type Ticker interface {
+	// C returns the channel the ticks are sent on.
+	C() <-chan time.Time
+
+	// Stop turns the ticker off. No more ticks are sent after it returns.
+	Stop()
+}
+
+// systemClock is the Clock of the system, used by default.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return currentTime()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+// systemTicker is the Ticker of the system Clock.
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t systemTicker) Stop() {
+	t.t.Stop()
+}
+
+// clock returns the Clock of the Logger.
+func (l *Logger) clock() Clock {
+	if l.Clock != nil {
+		return l.Clock
+	}
+	return systemClock{}
+}
+
+// now returns the current time of the Clock of the Logger.
+func (l *Logger) now() time.Time {
+	return l.clock().Now()
+}
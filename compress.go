@@ -0,0 +1,151 @@
+package logrotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// verifyCompressed checks that the gzip file at path decompresses to size
+// bytes with the given CRC-32 checksum.
+func verifyCompressed(fsys FS, path string, size int64, sum uint32) error {
+	f, err := open(fsys, path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	crc := crc32.NewIEEE()
+	// The gzip reader also checks the CRC and length in the gzip trailer.
+	n, err := io.Copy(crc, gz)
+	if err != nil {
+		return err
+	}
+	if n != size || crc.Sum32() != sum {
+		return fmt.Errorf("compressed log file %s does not match the original", path)
+	}
+	return nil
+}
+
+// checksumCRC32 returns the size and CRC-32 checksum of the file at path.
+func checksumCRC32(fsys FS, path string) (int64, uint32, error) {
+	f, err := open(fsys, path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	crc := crc32.NewIEEE()
+	n, err := io.Copy(crc, f)
+	return n, crc.Sum32(), err
+}
+
+// syncDir flushes the directory entries of dir to disk. Errors are ignored,
+// as not every platform supports syncing a directory.
+func syncDir(fsys FS, dir string) {
+	if d, err := open(fsys, dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// reconcile cleans up after a compression or encryption interrupted by a
+// crash: leftover temporary files are removed, and when both a backup and its
+// compressed or encrypted version exist, the new one is kept only if it
+// verifies against the original.
+func (l *Logger) reconcile() error {
+	entries, err := l.fs().ReadDir(l.dir())
+	if err != nil {
+		return fmt.Errorf("can't read log file directory: %s", err)
+	}
+	names := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		names[e.Name()] = true
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			continue
+		}
+		backup, errName := l.isBackupName(strings.TrimSuffix(name, tmpSuffix))
+		if errName != nil {
+			return errName
+		}
+		if !backup {
+			continue
+		}
+		path := filepath.Join(l.dir(), name)
+		if strings.HasSuffix(name, tmpSuffix) {
+			errRemove := l.fs().Remove(path)
+			if err == nil && errRemove != nil {
+				err = errRemove
+			}
+			continue
+		}
+		for _, suffix := range []string{compressSuffix, encryptSuffix} {
+			if !names[name+suffix] {
+				continue
+			}
+			size, sum, errSum := checksumCRC32(l.fs(), path)
+			if errSum != nil {
+				if err == nil {
+					err = errSum
+				}
+				break
+			}
+			// The rename only happens once the new file has been
+			// verified, so only the removal of the original was
+			// missed, unless something else happened to the file.
+			remove := path
+			if l.verifyTransformed(path+suffix, size, sum) != nil {
+				remove = path + suffix
+			}
+			errRemove := l.fs().Remove(remove)
+			if err == nil && errRemove != nil {
+				err = errRemove
+			}
+			break
+		}
+	}
+	return err
+}
+
+// verifyTransformed checks the compressed or encrypted file at path against
+// the size and CRC-32 checksum of the original. Encrypted files are trusted
+// when the Encrypter can't decrypt.
+func (l *Logger) verifyTransformed(path string, size int64, sum uint32) error {
+	if !isEncrypted(path) {
+		return verifyCompressed(l.fs(), path, size, sum)
+	}
+	dec, ok := l.Encrypter.(Decrypter)
+	if !ok {
+		return nil
+	}
+	return verifyEncrypted(l.fs(), path, size, sum, dec)
+}
+
+// isBackupName reports whether name is the name of a backup of the Logger,
+// compressed, encrypted or not. It fails if the time zone of the Logger
+// can't be loaded, as names can't be told apart without it.
+func (l *Logger) isBackupName(name string) (bool, error) {
+	if _, err := l.location(); err != nil {
+		return false, err
+	}
+	prefix, ext := l.prefixAndExt()
+	for _, suffix := range backupSuffixes {
+		if l.FilenameTimeFormat != "" {
+			if _, err := l.timeFromName(name, prefix, ext+suffix); err == nil {
+				return true, nil
+			}
+		} else if _, err := l.orderFromName(name, prefix, ext+suffix); err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,72 @@
+package logrotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"testing"
+)
+
+func TestCompressLogFileVerifies(t *testing.T) {
+	dir := makeTempDir("TestCompressLogFileVerifies", t)
+	defer os.RemoveAll(dir)
+
+	src := backupFileWithOrder(dir, 1)
+	b := []byte("boo!")
+	err := os.WriteFile(src, b, 0644)
+	isNil(err, t)
+
+	err = compressLogFile(OSFS{}, src, src+compressSuffix)
+	isNil(err, t)
+	notExist(src, t)
+	notExist(src+compressSuffix+tmpSuffix, t)
+	existsWithContent(src+compressSuffix, gzipped(b, t), t)
+
+	err = verifyCompressed(OSFS{}, src+compressSuffix, int64(len(b)), 0)
+	notNil(err, t)
+}
+
+func TestReconcileAfterCrash(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReconcileAfterCrash", t)
+	defer os.RemoveAll(dir)
+
+	b := []byte("boo!")
+	// Crashed while writing the compressed file of the first backup.
+	first := backupFileWithOrder(dir, 1)
+	isNil(os.WriteFile(first, b, 0644), t)
+	isNil(os.WriteFile(first+compressSuffix+tmpSuffix, gzipped(b, t)[:5], 0644), t)
+	// Crashed before removing the second backup once compressed.
+	second := backupFileWithOrder(dir, 2)
+	isNil(os.WriteFile(second, b, 0644), t)
+	isNil(os.WriteFile(second+compressSuffix, gzipped(b, t), 0644), t)
+	// Truncated compressed file of the third backup.
+	third := backupFileWithOrder(dir, 3)
+	isNil(os.WriteFile(third, b, 0644), t)
+	isNil(os.WriteFile(third+compressSuffix, gzipped(b, t)[:10], 0644), t)
+
+	l := &Logger{
+		Filename: logFile(dir),
+	}
+	defer l.Close()
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	existsWithContent(first, b, t)
+	notExist(first+compressSuffix+tmpSuffix, t)
+	notExist(second, t)
+	existsWithContent(second+compressSuffix, gzipped(b, t), t)
+	existsWithContent(third, b, t)
+	notExist(third+compressSuffix, t)
+	fileCount(dir, 4, t)
+}
+
+// gzipped returns b compressed with gzip.
+func gzipped(b []byte, t testing.TB) []byte {
+	bc := new(bytes.Buffer)
+	gz := gzip.NewWriter(bc)
+	_, err := gz.Write(b)
+	isNilUp(err, t, 1)
+	isNilUp(gz.Close(), t, 1)
+	return bc.Bytes()
+}
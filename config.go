@@ -0,0 +1,354 @@
+package logrotate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Config holds the settings of a Logger. It mirrors the exported fields of
+// Logger and can be applied to a running Logger with Reconfigure.
+type Config struct {
+	// Filename is the file to write logs to. See Logger.Filename.
+	Filename string `json:"filename" yaml:"filename"`
+
+	// FilenameTimeFormat defines the timestamp format of rotated file names.
+	// See Logger.FilenameTimeFormat.
+	FilenameTimeFormat string `json:"filenameTimeFormat" yaml:"filenameTimeFormat"`
+
+	// FileOrder is the starting order of old log file. It is only applied by
+	// Reconfigure when the log file name changes, so that reloading a config
+	// file does not reset the order of the current backups.
+	FileOrder int `json:"fileOrder" yaml:"fileOrder"`
+
+	// MaxBytes is the maximum size in bytes of the log file before it gets
+	// rotated. See Logger.MaxBytes.
+	MaxBytes int64 `json:"maxbytes" yaml:"maxbytes"`
+
+	// MaxAge is the maximum number of days to retain old log files.
+	MaxAge int `json:"maxage" yaml:"maxage"`
+
+	// MaxAgeDuration is the maximum age of old log files. See
+	// Logger.MaxAgeDuration.
+	MaxAgeDuration time.Duration `json:"maxageduration" yaml:"maxageduration"`
+
+	// CalendarDays determines if MaxAge counts calendar days. See
+	// Logger.CalendarDays.
+	CalendarDays bool `json:"calendardays" yaml:"calendardays"`
+
+	// MaxBackups is the maximum number of old log files to retain.
+	MaxBackups int `json:"maxbackups" yaml:"maxbackups"`
+
+	// LocalTime determines if the time used for formatting the timestamps in
+	// backup files is the computer's local time.
+	//
+	// Deprecated: set TimeZone to "Local" instead.
+	LocalTime bool `json:"localtime" yaml:"localtime"`
+
+	// TimeZone is the name of the time zone of the Logger. See
+	// Logger.TimeZone.
+	TimeZone string `json:"timezone" yaml:"timezone"`
+
+	// Compress determines if the rotated log files should be compressed.
+	Compress bool `json:"compress" yaml:"compress"`
+
+	// FileMode is the permission of new log files. See Logger.FileMode.
+	FileMode os.FileMode `json:"filemode" yaml:"filemode"`
+
+	// BackupMode is the permission of backups. See Logger.BackupMode.
+	BackupMode os.FileMode `json:"backupmode" yaml:"backupmode"`
+
+	// DirMode is the permission of created directories. See Logger.DirMode.
+	DirMode os.FileMode `json:"dirmode" yaml:"dirmode"`
+
+	// Owner and Group are the user and group of log files. See Logger.Owner.
+	Owner string `json:"owner" yaml:"owner"`
+	Group string `json:"group" yaml:"group"`
+
+	// Manifest determines if a manifest of the backups is kept. See
+	// Logger.Manifest.
+	Manifest bool `json:"manifest" yaml:"manifest"`
+
+	// Checksum determines if backups are chained for tamper evidence. See
+	// Logger.Checksum.
+	Checksum bool `json:"checksum" yaml:"checksum"`
+
+	// RotateOnOpen determines if the log file is rotated when it is first
+	// opened. See Logger.RotateOnOpen.
+	RotateOnOpen bool `json:"rotateonopen" yaml:"rotateonopen"`
+
+	// MaxFileAge is the maximum age of the log file before it gets rotated.
+	// See Logger.MaxFileAge.
+	MaxFileAge time.Duration `json:"maxfileage" yaml:"maxfileage"`
+
+	// CleanupInterval is how often old log files are cleaned up in the
+	// background. See Logger.CleanupInterval.
+	CleanupInterval time.Duration `json:"cleanupinterval" yaml:"cleanupinterval"`
+}
+
+// Config returns a snapshot of the current settings of the Logger.
+func (l *Logger) Config() Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.config()
+}
+
+// config returns the current settings. The caller must hold l.mu.
+func (l *Logger) config() Config {
+	return Config{
+		Filename:           l.Filename,
+		FilenameTimeFormat: l.FilenameTimeFormat,
+		FileOrder:          l.FileOrder,
+		MaxBytes:           l.MaxBytes,
+		MaxAge:             l.MaxAge,
+		MaxAgeDuration:     l.MaxAgeDuration,
+		CalendarDays:       l.CalendarDays,
+		MaxBackups:         l.MaxBackups,
+		LocalTime:          l.LocalTime,
+		TimeZone:           l.TimeZone,
+		Compress:           l.Compress,
+		FileMode:           l.FileMode,
+		BackupMode:         l.BackupMode,
+		DirMode:            l.DirMode,
+		Owner:              l.Owner,
+		Group:              l.Group,
+		Manifest:           l.Manifest,
+		Checksum:           l.Checksum,
+		RotateOnOpen:       l.RotateOnOpen,
+		MaxFileAge:         l.MaxFileAge,
+		CleanupInterval:    l.CleanupInterval,
+	}
+}
+
+// derive returns a new Logger writing to filename with all the settings of
+// the Logger, including those that are not part of Config such as its
+// Encrypter and FS.
+func (l *Logger) derive(filename string) *Logger {
+	l.mu.Lock()
+	cfg := l.config()
+	d := &Logger{
+		Location:   l.Location,
+		SigningKey: l.SigningKey,
+		VerifyKey:  l.VerifyKey,
+		Encrypter:  l.Encrypter,
+		FS:         l.FS,
+		Clock:      l.Clock,
+		Retention:  l.Retention,
+	}
+	l.mu.Unlock()
+
+	cfg.Filename = filename
+	// The new Logger has no file open, so applying cfg can't fail.
+	d.Reconfigure(cfg)
+	return d
+}
+
+// Reconfigure atomically applies cfg to the Logger. It is safe to call
+// concurrently with Write, unlike assigning the exported fields directly.
+//
+// If the log file name changes, the current file is closed and the new one is
+// opened (or created) in its place. If only the naming scheme of backups
+// changes, the current file is rotated so that the new scheme takes effect
+// immediately. In any case old log files are then compressed and removed
+// according to the new retention settings.
+func (l *Logger) Reconfigure(cfg Config) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A time zone that can't be loaded is rejected before anything changes.
+	oldZone := l.TimeZone
+	l.TimeZone = cfg.TimeZone
+	if _, err := l.location(); err != nil {
+		l.TimeZone = oldZone
+		return err
+	}
+
+	oldName := l.filename()
+	oldFormat := l.FilenameTimeFormat
+
+	l.Filename = cfg.Filename
+	l.FilenameTimeFormat = cfg.FilenameTimeFormat
+	l.MaxBytes = cfg.MaxBytes
+	l.MaxAge = cfg.MaxAge
+	l.MaxAgeDuration = cfg.MaxAgeDuration
+	l.CalendarDays = cfg.CalendarDays
+	l.MaxBackups = cfg.MaxBackups
+	l.LocalTime = cfg.LocalTime
+	l.TimeZone = cfg.TimeZone
+	l.Compress = cfg.Compress
+	l.FileMode = cfg.FileMode
+	l.BackupMode = cfg.BackupMode
+	l.DirMode = cfg.DirMode
+	l.Owner = cfg.Owner
+	l.Group = cfg.Group
+	l.Manifest = cfg.Manifest
+	l.Checksum = cfg.Checksum
+	l.RotateOnOpen = cfg.RotateOnOpen
+	l.MaxFileAge = cfg.MaxFileAge
+	l.CleanupInterval = cfg.CleanupInterval
+
+	renamed := l.filename() != oldName
+	if renamed {
+		l.FileOrder = cfg.FileOrder
+		l.orderScanned = false
+	}
+
+	// Settings are picked up by the next Write, which also runs millRun.
+	if l.file == nil {
+		return nil
+	}
+	l.startJanitor()
+
+	switch {
+	case renamed:
+		if err := l.close(); err != nil {
+			return err
+		}
+		return l.openExistingOrNew()
+	case l.FilenameTimeFormat != oldFormat, l.size >= l.max(0), l.stale():
+		return l.rotate()
+	}
+	return l.millRun()
+}
+
+// LoadConfig reads a Config from the given file. The format is chosen from
+// the file extension: `.json`, `.yaml`, `.yml` or `.toml`.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("can't read config file: %s", err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cfg)
+	case ".toml":
+		_, err = toml.Decode(string(data), &cfg)
+	default:
+		return cfg, fmt.Errorf("unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("can't decode config file: %s", err)
+	}
+	return cfg, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Durations are accepted as text,
+// such as "24h", like in YAML and TOML, as well as numbers of nanoseconds.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	// plain has the fields of Config but not this method.
+	type plain Config
+	aux := struct {
+		*plain
+		MaxAgeDuration  jsonDuration `json:"maxageduration"`
+		MaxFileAge      jsonDuration `json:"maxfileage"`
+		CleanupInterval jsonDuration `json:"cleanupinterval"`
+	}{
+		plain:           (*plain)(c),
+		MaxAgeDuration:  jsonDuration(c.MaxAgeDuration),
+		MaxFileAge:      jsonDuration(c.MaxFileAge),
+		CleanupInterval: jsonDuration(c.CleanupInterval),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	c.MaxAgeDuration = time.Duration(aux.MaxAgeDuration)
+	c.MaxFileAge = time.Duration(aux.MaxFileAge)
+	c.CleanupInterval = time.Duration(aux.CleanupInterval)
+	return nil
+}
+
+// jsonDuration is a time.Duration decoded from JSON text or a number.
+type jsonDuration time.Duration
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid duration %s", data)
+		}
+		*d = jsonDuration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = jsonDuration(v)
+	return nil
+}
+
+// WatchConfig loads the config file at path, applies it with Reconfigure and
+// then polls the file every interval, reapplying it whenever its modification
+// time or size changes. Errors that occur after the initial load are reported
+// to onError, which may be nil. Watching stops when ctx is done. interval must
+// be positive.
+func (l *Logger) WatchConfig(ctx context.Context, path string, interval time.Duration, onError func(error)) error {
+	return watchConfig(ctx, path, interval, onError, l.clock(), l.Reconfigure)
+}
+
+// watchConfig implements WatchConfig, applying the config file with
+// reconfigure and polling it on clock.
+func watchConfig(ctx context.Context, path string, interval time.Duration, onError func(error), clock Clock, reconfigure func(Config) error) error {
+	if interval <= 0 {
+		return fmt.Errorf("can't watch config file: non-positive interval %s", interval)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("can't stat config file: %s", err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := reconfigure(cfg); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+			cur, err := os.Stat(path)
+			if err != nil {
+				report(onError, fmt.Errorf("can't stat config file: %s", err))
+				continue
+			}
+			if cur.ModTime().Equal(info.ModTime()) && cur.Size() == info.Size() {
+				continue
+			}
+			info = cur
+			cfg, err := LoadConfig(path)
+			if err == nil {
+				err = reconfigure(cfg)
+			}
+			report(onError, err)
+		}
+	}()
+	return nil
+}
+
+// report passes a non-nil err to fn if fn is set.
+func report(fn func(error), err error) {
+	if fn != nil && err != nil {
+		fn(err)
+	}
+}
@@ -0,0 +1,173 @@
+package logrotate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReconfigure(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReconfigure", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		MaxBytes: 100,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	cfg := l.Config()
+	cfg.MaxBytes = 4
+	err = l.Reconfigure(cfg)
+	isNil(err, t)
+
+	// the lowered limit has been reached, so the file has been rotated.
+	existsWithContent(filename, []byte{}, t)
+	existsWithContent(backupFileWithOrder(dir, 1), b, t)
+	fileCount(dir, 2, t)
+	equals(int64(4), l.MaxBytes, t)
+}
+
+func TestReconfigureFilename(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReconfigureFilename", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	newName := filepath.Join(dir, "other.log")
+	err = l.Reconfigure(Config{Filename: newName})
+	isNil(err, t)
+
+	b2 := []byte("foo!")
+	_, err = l.Write(b2)
+	isNil(err, t)
+
+	existsWithContent(logFile(dir), b, t)
+	existsWithContent(newName, b2, t)
+	fileCount(dir, 2, t)
+}
+
+func TestReconfigureNamingScheme(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReconfigureNamingScheme", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	err = l.Reconfigure(Config{Filename: filename, FilenameTimeFormat: backupTimeFormat})
+	isNil(err, t)
+
+	existsWithContent(filename, []byte{}, t)
+	existsWithContent(backupFileWithTime(dir, backupTimeFormat), b, t)
+	fileCount(dir, 2, t)
+}
+
+func TestWatchConfig(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestWatchConfig", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	cfgFile := filepath.Join(dir, "logger.yaml")
+	err := os.WriteFile(cfgFile, []byte("filename: "+filename+"\nmaxbytes: 100\n"), 0644)
+	isNil(err, t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &Logger{}
+	defer l.Close()
+	errs := make(chan error, 1)
+	err = l.WatchConfig(ctx, cfgFile, 10*time.Millisecond, func(err error) { errs <- err })
+	isNil(err, t)
+	equals(int64(100), l.Config().MaxBytes, t)
+
+	err = os.WriteFile(cfgFile, []byte("filename: "+filename+"\nmaxbytes: 5\nmaxbackups: 2\n"), 0644)
+	isNil(err, t)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for l.Config().MaxBytes != 5 && time.Now().Before(deadline) {
+		select {
+		case err := <-errs:
+			t.Fatal(err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	equals(int64(5), l.Config().MaxBytes, t)
+	equals(2, l.Config().MaxBackups, t)
+
+	// a non-positive interval is rejected before anything is applied.
+	other := &Logger{}
+	defer other.Close()
+	notNil(other.WatchConfig(ctx, cfgFile, 0, nil), t)
+	equals(Config{}, other.Config(), t)
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := makeTempDir("TestLoadConfig", t)
+	defer os.RemoveAll(dir)
+
+	exp := Config{
+		Filename:        "foo",
+		MaxBytes:        5,
+		Compress:        true,
+		MaxAgeDuration:  90 * time.Minute,
+		MaxFileAge:      24 * time.Hour,
+		CleanupInterval: time.Hour,
+	}
+	files := map[string]string{
+		"logger.toml": "filename = \"foo\"\nmaxbytes = 5\ncompress = true\n" +
+			"maxageduration = \"1h30m\"\nmaxfileage = \"24h\"\ncleanupinterval = \"1h\"\n",
+		"logger.yaml": "filename: foo\nmaxbytes: 5\ncompress: true\n" +
+			"maxageduration: 1h30m\nmaxfileage: 24h\ncleanupinterval: 1h\n",
+		"logger.json": `{"filename": "foo", "maxbytes": 5, "compress": true, ` +
+			`"maxageduration": "1h30m", "maxfileage": "24h", "cleanupinterval": 3600000000000}`,
+	}
+	for name, content := range files {
+		cfgFile := filepath.Join(dir, name)
+		err := os.WriteFile(cfgFile, []byte(content), 0644)
+		isNil(err, t)
+		cfg, err := LoadConfig(cfgFile)
+		isNil(err, t)
+		equals(exp, cfg, t)
+	}
+
+	err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"maxfileage": "a day"}`), 0644)
+	isNil(err, t)
+	_, err = LoadConfig(filepath.Join(dir, "bad.json"))
+	notNil(err, t)
+
+	_, err = LoadConfig(filepath.Join(dir, "logger.ini"))
+	notNil(err, t)
+}
+
+func TestConfigJSONRoundTrip(t *testing.T) {
+	cfg := Config{Filename: "foo", MaxBackups: 3, MaxFileAge: time.Hour, CleanupInterval: time.Minute}
+	data, err := json.Marshal(cfg)
+	isNil(err, t)
+	var got Config
+	isNil(json.Unmarshal(data, &got), t)
+	equals(cfg, got, t)
+}
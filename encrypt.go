@@ -0,0 +1,347 @@
+package logrotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+const (
+	encryptSuffix = ".enc"
+
+	// aesGCMMagic starts every file encrypted by AESGCM.
+	aesGCMMagic = "LRAESGCM1"
+	// aesGCMChunkSize is the size of the plaintext chunks sealed by AESGCM.
+	aesGCMChunkSize = 64 * 1024
+	// aesGCMFinal flags the length of the last chunk.
+	aesGCMFinal = 1 << 31
+)
+
+// backupSuffixes are the suffixes a backup can have after its original name.
+var backupSuffixes = []string{"", compressSuffix, encryptSuffix, compressSuffix + encryptSuffix}
+
+// Encrypter encrypts backups at rest.
+type Encrypter interface {
+	// NewWriter returns a writer encrypting everything written to it to w.
+	// Closing it must flush the encrypted data, but not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// Decrypter decrypts what an Encrypter encrypted.
+type Decrypter interface {
+	// NewReader returns a reader decrypting the data read from r.
+	NewReader(r io.Reader) (io.Reader, error)
+}
+
+// baseBackupName returns the name of a backup without its compression and
+// encryption suffixes.
+func baseBackupName(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, encryptSuffix), compressSuffix)
+}
+
+// isCompressed reports whether the backup named name is compressed.
+func isCompressed(name string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(name, encryptSuffix), compressSuffix)
+}
+
+// isEncrypted reports whether the backup named name is encrypted.
+func isEncrypted(name string) bool {
+	return strings.HasSuffix(name, encryptSuffix)
+}
+
+// encryptLogFile encrypts the given log file with enc, removing the
+// unencrypted log file if successful.
+func encryptLogFile(fsys FS, src, dst string, enc Encrypter) error {
+	var verify func(string, int64, uint32) error
+	if dec, ok := enc.(Decrypter); ok {
+		verify = func(path string, size int64, sum uint32) error {
+			return verifyEncrypted(fsys, path, size, sum, dec)
+		}
+	}
+	if err := transformLogFile(fsys, src, dst, enc.NewWriter, verify); err != nil {
+		return fmt.Errorf("failed to encrypt log file: %v", err)
+	}
+	return nil
+}
+
+// verifyEncrypted checks that the file at path decrypts with dec to size
+// bytes with the given CRC-32 checksum.
+func verifyEncrypted(fsys FS, path string, size int64, sum uint32, dec Decrypter) error {
+	f, err := open(fsys, path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := dec.NewReader(f)
+	if err != nil {
+		return err
+	}
+	crc := crc32.NewIEEE()
+	n, err := io.Copy(crc, r)
+	if err != nil {
+		return err
+	}
+	if n != size || crc.Sum32() != sum {
+		return fmt.Errorf("encrypted log file %s does not match the original", path)
+	}
+	return nil
+}
+
+// backupReader returns a reader over the original content of the backup
+// named name read from r, decrypting and decompressing it as needed.
+func (l *Logger) backupReader(r io.Reader, name string) (io.Reader, error) {
+	if isEncrypted(name) {
+		dec, ok := l.Encrypter.(Decrypter)
+		if !ok {
+			return nil, fmt.Errorf("can't decrypt %s: no Decrypter", name)
+		}
+		var err error
+		if r, err = dec.NewReader(r); err != nil {
+			return nil, fmt.Errorf("can't decrypt %s: %s", name, err)
+		}
+	}
+	if isCompressed(name) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("can't decompress %s: %s", name, err)
+		}
+		r = gz
+	}
+	return r, nil
+}
+
+// AESGCM is an Encrypter and Decrypter using AES-GCM. Data is sealed in
+// chunks, so that it is streamed rather than held in memory, and the last
+// chunk is flagged so that truncation is detected. Every encrypted file
+// starts with the ID of the key it was encrypted with, so that the key used
+// for new backups can be changed while older keys remain available to read
+// older backups.
+type AESGCM struct {
+	keyID string
+	keys  map[string][]byte
+}
+
+// ensure we always implement Encrypter and Decrypter
+var (
+	_ Encrypter = (*AESGCM)(nil)
+	_ Decrypter = (*AESGCM)(nil)
+)
+
+// NewAESGCM returns an AESGCM encrypting with the key keys[keyID] and
+// decrypting with any key of keys. Keys must be 16, 24 or 32 bytes long to
+// select AES-128, AES-192 or AES-256. Key IDs are at most 255 bytes long.
+func NewAESGCM(keyID string, keys map[string][]byte) (*AESGCM, error) {
+	if _, ok := keys[keyID]; !ok {
+		return nil, fmt.Errorf("unknown key ID %q", keyID)
+	}
+	for id, key := range keys {
+		if len(id) > 255 {
+			return nil, fmt.Errorf("key ID %q is too long", id)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("invalid key %q: %s", id, err)
+		}
+	}
+	return &AESGCM{keyID: keyID, keys: keys}, nil
+}
+
+// aead returns the AES-GCM cipher of the key with the given ID.
+func (a *AESGCM) aead(keyID string) (cipher.AEAD, error) {
+	key, ok := a.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", keyID)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// NewWriter implements Encrypter. The header written to w holds a magic
+// string, the key ID and a random nonce prefix; each chunk that follows is
+// its 4-byte big endian length, with the top bit set on the last one, and
+// the sealed data.
+func (a *AESGCM) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	aead, err := a.aead(a.keyID)
+	if err != nil {
+		return nil, err
+	}
+	header := []byte(aesGCMMagic)
+	header = append(header, byte(len(a.keyID)))
+	header = append(header, a.keyID...)
+	prefix := make([]byte, aead.NonceSize()-4)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	header = append(header, prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &aesGCMWriter{
+		w:      w,
+		aead:   aead,
+		header: header,
+		prefix: prefix,
+		buf:    make([]byte, 0, aesGCMChunkSize),
+	}, nil
+}
+
+// NewReader implements Decrypter.
+func (a *AESGCM) NewReader(r io.Reader) (io.Reader, error) {
+	magic := make([]byte, len(aesGCMMagic)+1)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, fmt.Errorf("can't read header: %s", err)
+	}
+	if string(magic[:len(aesGCMMagic)]) != aesGCMMagic {
+		return nil, errors.New("not encrypted with AES-GCM")
+	}
+	keyID := make([]byte, magic[len(aesGCMMagic)])
+	if _, err := io.ReadFull(r, keyID); err != nil {
+		return nil, fmt.Errorf("can't read header: %s", err)
+	}
+	aead, err := a.aead(string(keyID))
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, aead.NonceSize()-4)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, fmt.Errorf("can't read header: %s", err)
+	}
+	header := append(append(magic, keyID...), prefix...)
+	return &aesGCMReader{r: r, aead: aead, header: header, prefix: prefix}, nil
+}
+
+// aesGCMNonce returns the nonce of the chunk with the given sequence number.
+func aesGCMNonce(prefix []byte, seq uint32) []byte {
+	return binary.BigEndian.AppendUint32(append([]byte(nil), prefix...), seq)
+}
+
+// aesGCMData returns the additional data authenticated with a chunk, which
+// binds it to the header and to whether it is the last one.
+func aesGCMData(header []byte, final bool) []byte {
+	flag := byte(0)
+	if final {
+		flag = 1
+	}
+	return append(append([]byte(nil), header...), flag)
+}
+
+// aesGCMWriter is the writer returned by AESGCM.NewWriter.
+type aesGCMWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	buf    []byte
+	seq    uint32
+	closed bool
+}
+
+func (w *aesGCMWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed encrypter")
+	}
+	n := 0
+	for len(p) > 0 {
+		c := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+		// Keep a full chunk buffered, as it may turn out to be the last.
+		if len(w.buf) == cap(w.buf) && len(p) > 0 {
+			if err := w.seal(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close seals the last chunk. It does not close the underlying writer.
+func (w *aesGCMWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+// seal writes the buffered data as a chunk.
+func (w *aesGCMWriter) seal(final bool) error {
+	if w.seq == 1<<32-1 {
+		return errors.New("too much data to encrypt")
+	}
+	sealed := w.aead.Seal(nil, aesGCMNonce(w.prefix, w.seq), w.buf, aesGCMData(w.header, final))
+	w.seq++
+	w.buf = w.buf[:0]
+	length := uint32(len(sealed))
+	if final {
+		length |= aesGCMFinal
+	}
+	if _, err := w.w.Write(binary.BigEndian.AppendUint32(nil, length)); err != nil {
+		return err
+	}
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+// aesGCMReader is the reader returned by AESGCM.NewReader.
+type aesGCMReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	plain  bytes.Buffer
+	seq    uint32
+	done   bool
+}
+
+func (r *aesGCMReader) Read(p []byte) (int, error) {
+	for r.plain.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	return r.plain.Read(p)
+}
+
+// open reads and opens the next chunk.
+func (r *aesGCMReader) open() error {
+	var length [4]byte
+	if _, err := io.ReadFull(r.r, length[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("truncated encrypted data: %s", err)
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	final := n&aesGCMFinal != 0
+	n &^= aesGCMFinal
+	if n > aesGCMChunkSize+uint32(r.aead.Overhead()) {
+		return errors.New("invalid encrypted chunk length")
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(r.r, sealed); err != nil {
+		return fmt.Errorf("truncated encrypted data: %s", err)
+	}
+	plain, err := r.aead.Open(nil, aesGCMNonce(r.prefix, r.seq), sealed, aesGCMData(r.header, final))
+	if err != nil {
+		return fmt.Errorf("can't decrypt: %s", err)
+	}
+	r.seq++
+	r.plain.Write(plain)
+	r.done = final
+	return nil
+}
@@ -0,0 +1,131 @@
+package logrotate
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAESGCMRoundTrip(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 16)
+	oldEnc, err := NewAESGCM("old", map[string][]byte{"old": oldKey})
+	isNil(err, t)
+	enc, err := NewAESGCM("new", map[string][]byte{"old": oldKey, "new": newKey})
+	isNil(err, t)
+
+	_, err = NewAESGCM("missing", map[string][]byte{"old": oldKey})
+	notNil(err, t)
+	_, err = NewAESGCM("bad", map[string][]byte{"bad": []byte("short")})
+	notNil(err, t)
+
+	data := bytes.Repeat([]byte("0123456789"), aesGCMChunkSize/4)
+	for _, e := range []*AESGCM{oldEnc, enc} {
+		var buf bytes.Buffer
+		w, err := e.NewWriter(&buf)
+		isNil(err, t)
+		_, err = w.Write(data)
+		isNil(err, t)
+		isNil(w.Close(), t)
+		sealed := buf.Bytes()
+
+		// The new key set still decrypts what the old key encrypted.
+		r, err := enc.NewReader(bytes.NewReader(sealed))
+		isNil(err, t)
+		got, err := io.ReadAll(r)
+		isNil(err, t)
+		equals(data, got, t)
+
+		// Truncation at a chunk boundary is detected.
+		r, err = enc.NewReader(bytes.NewReader(sealed[:len(sealed)/2]))
+		isNil(err, t)
+		_, err = io.ReadAll(r)
+		notNil(err, t)
+	}
+
+	var buf bytes.Buffer
+	w, err := enc.NewWriter(&buf)
+	isNil(err, t)
+	isNil(w.Close(), t)
+	_, err = oldEnc.NewReader(&buf)
+	notNil(err, t)
+}
+
+func TestEncryptBackups(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestEncryptBackups", t)
+	defer os.RemoveAll(dir)
+
+	enc, err := NewAESGCM("k1", map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)})
+	isNil(err, t)
+	l := &Logger{
+		Filename:  logFile(dir),
+		Compress:  true,
+		Checksum:  true,
+		Encrypter: enc,
+	}
+	defer l.Close()
+	for _, s := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+	}
+	_, err = l.Write([]byte("three!"))
+	isNil(err, t)
+
+	encrypted := backupFileWithOrder(dir, 1) + compressSuffix + encryptSuffix
+	exists(encrypted, t)
+	exists(backupFileWithOrder(dir, 2)+compressSuffix+encryptSuffix, t)
+	notExist(backupFileWithOrder(dir, 1), t)
+	notExist(backupFileWithOrder(dir, 1)+compressSuffix, t)
+	b, err := os.ReadFile(encrypted)
+	isNil(err, t)
+	assert(!bytes.Contains(b, []byte("one!")), t, "expected the backup to be encrypted")
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals(true, backups[0].Compressed, t)
+	equals(true, backups[0].Encrypted, t)
+
+	r, err := NewReader(l, nil)
+	isNil(err, t)
+	b, err = io.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals("one!two!three!", string(b), t)
+
+	issues, err := l.Verify()
+	isNil(err, t)
+	equals(0, len(issues), t)
+}
+
+func TestReconcileEncrypted(t *testing.T) {
+	currentTime = time.Now
+	dir := makeTempDir("TestReconcileEncrypted", t)
+	defer os.RemoveAll(dir)
+
+	enc, err := NewAESGCM("k1", map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)})
+	isNil(err, t)
+	b := []byte("boo!")
+	backup := backupFileWithOrder(dir, 1)
+	isNil(os.WriteFile(backup, b, 0644), t)
+	isNil(encryptLogFile(OSFS{}, backup, backup+encryptSuffix, enc), t)
+	notExist(backup, t)
+	// Crashed before removing the original once encrypted.
+	isNil(os.WriteFile(backup, b, 0644), t)
+
+	l := &Logger{
+		Filename:  logFile(dir),
+		Encrypter: enc,
+	}
+	defer l.Close()
+	_, err = l.Write(b)
+	isNil(err, t)
+	notExist(backup, t)
+	exists(backup+encryptSuffix, t)
+	fileCount(dir, 2, t)
+}
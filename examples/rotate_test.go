@@ -4,6 +4,7 @@
 package logrotate
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -26,3 +27,20 @@ func ExampleLogger_Rotate() {
 		}
 	}()
 }
+
+// Example of how to rotate on SIGHUP and reopen on SIGUSR1, e.g. from the
+// postrotate script of the system logrotate.
+func ExampleLogger_HandleSignals() {
+	l := &logrotate.Logger{}
+	log.SetOutput(l)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.HandleSignals(ctx,
+		logrotate.RotateOn(syscall.SIGHUP),
+		logrotate.ReopenOn(syscall.SIGUSR1),
+		logrotate.OnSignalError(func(sig os.Signal, err error) {
+			log.Printf("can't handle %s: %v", sig, err)
+		}),
+	)
+}
@@ -0,0 +1,186 @@
+package logrotate
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// follower is the state of a Follow call. The Logger queues a read handle
+// on every file it starts writing to, and wakes the follower on every write.
+type follower struct {
+	mu    sync.Mutex
+	files []File
+	wake  chan struct{}
+}
+
+// Follow returns a channel receiving the bytes written to the log file from
+// now on, like `tail -F`. Rotations are followed without polling: every time
+// the Logger switches to a new file, the previous one is read to its end
+// before reading the new one, so no bytes are lost or duplicated. When the
+// Logger reopens the file it was writing to, such as after Close or Reopen,
+// reading simply goes on, provided the FS implements SameFiler. The channel is
+// closed when ctx is done.
+func (l *Logger) Follow(ctx context.Context) (<-chan []byte, error) {
+	fl := &follower{wake: make(chan struct{}, 1)}
+
+	l.mu.Lock()
+	if l.file != nil {
+		f, err := openAt(l.fs(), l.filename(), l.size)
+		if err != nil {
+			l.mu.Unlock()
+			return nil, err
+		}
+		fl.files = append(fl.files, f)
+	}
+	l.followers = append(l.followers, fl)
+	l.mu.Unlock()
+
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		defer l.unfollow(fl)
+		fl.run(ctx, ch)
+	}()
+	return ch, nil
+}
+
+// openAt opens name for reading at the given offset.
+func openAt(fsys FS, name string, offset int64) (File, error) {
+	f, err := open(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// notifyFollowers hands the file the Logger has just started writing to over
+// to the followers. It must be called with l.mu held, once l.file and l.size
+// are set.
+func (l *Logger) notifyFollowers() {
+	if len(l.followers) == 0 {
+		return
+	}
+	info, errStat := l.file.Stat()
+	for _, fl := range l.followers {
+		if errStat == nil && fl.follows(l.fs(), info) {
+			// Reading the file from a second handle would repeat what is
+			// written to it from now on.
+			fl.signal()
+			continue
+		}
+		f, err := openAt(l.fs(), l.filename(), l.size)
+		if err != nil {
+			continue
+		}
+		fl.mu.Lock()
+		fl.files = append(fl.files, f)
+		fl.mu.Unlock()
+		fl.signal()
+	}
+}
+
+// follows reports whether the last file queued for the follower is the one
+// of fsys described by info.
+func (fl *follower) follows(fsys FS, info os.FileInfo) bool {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	if len(fl.files) == 0 {
+		return false
+	}
+	last, err := fl.files[len(fl.files)-1].Stat()
+	return err == nil && sameFile(fsys, last, info)
+}
+
+// signalFollowers wakes the followers up after a write. It must be called with
+// l.mu held.
+func (l *Logger) signalFollowers() {
+	for _, fl := range l.followers {
+		fl.signal()
+	}
+}
+
+// unfollow removes fl from the followers of the Logger.
+func (l *Logger) unfollow(fl *follower) {
+	l.mu.Lock()
+	for i, f := range l.followers {
+		if f == fl {
+			l.followers = append(l.followers[:i], l.followers[i+1:]...)
+			break
+		}
+	}
+	l.mu.Unlock()
+
+	fl.mu.Lock()
+	for _, f := range fl.files {
+		f.Close()
+	}
+	fl.files = nil
+	fl.mu.Unlock()
+}
+
+// signal wakes the follower up without blocking.
+func (fl *follower) signal() {
+	select {
+	case fl.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the content of the queued files to ch until ctx is done.
+func (fl *follower) run(ctx context.Context, ch chan<- []byte) {
+	for {
+		fl.mu.Lock()
+		var cur File
+		next := len(fl.files) > 1
+		if len(fl.files) > 0 {
+			cur = fl.files[0]
+		}
+		fl.mu.Unlock()
+
+		if cur != nil {
+			if !fl.drain(ctx, cur, ch) {
+				return
+			}
+			if next {
+				// The Logger had moved on before we reached the end,
+				// so what we just read is the whole rest of the file.
+				fl.mu.Lock()
+				cur.Close()
+				fl.files = fl.files[1:]
+				fl.mu.Unlock()
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-fl.wake:
+		}
+	}
+}
+
+// drain sends what is left to read from f to ch. It returns false if ctx is
+// done.
+func (fl *follower) drain(ctx context.Context, f File, ch chan<- []byte) bool {
+	for {
+		buf := make([]byte, 32*1024)
+		n, err := f.Read(buf)
+		if n > 0 {
+			select {
+			case ch <- buf[:n]:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if err != nil {
+			return ctx.Err() == nil
+		}
+	}
+}
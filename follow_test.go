@@ -0,0 +1,146 @@
+package logrotate
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFollow(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestFollow", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		MaxBytes: 10,
+		Compress: true,
+	}
+	defer l.Close()
+	_, err := l.Write([]byte("before!"))
+	isNil(err, t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := l.Follow(ctx)
+	isNil(err, t)
+
+	want := ""
+	for _, s := range []string{"one!", "two!", "three!", "four!", "five!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		want += s
+	}
+	isNil(l.Rotate(), t)
+	_, err = l.Write([]byte("six!"))
+	isNil(err, t)
+	want += "six!"
+
+	got := ""
+	timeout := time.After(5 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case b := <-ch:
+			got += string(b)
+		case <-timeout:
+			t.Fatalf("timed out, got %q", got)
+		}
+	}
+	equals(want, got, t)
+
+	cancel()
+	for range ch {
+	}
+	equals(0, len(l.followers), t)
+}
+
+func TestFollowBeforeFirstWrite(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestFollowBeforeFirstWrite", t)
+	defer os.RemoveAll(dir)
+
+	err := os.WriteFile(logFile(dir), []byte("old!"), 0644)
+	isNil(err, t)
+	l := &Logger{
+		Filename: logFile(dir),
+	}
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := l.Follow(ctx)
+	isNil(err, t)
+
+	_, err = l.Write([]byte("new!"))
+	isNil(err, t)
+
+	select {
+	case b := <-ch:
+		equals("new!", string(b), t)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestFollowReopenSameFile(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestFollowReopenSameFile", t)
+	defer os.RemoveAll(dir)
+
+	for _, fsys := range []FS{OSFS{}, NewMemFS(), sameFileFS{NewMemFS()}} {
+		l := &Logger{
+			Filename: logFile(dir),
+			FS:       fsys,
+		}
+		_, err := l.Write([]byte("a"))
+		isNil(err, t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ch, err := l.Follow(ctx)
+		isNil(err, t)
+
+		// the file is reopened in place twice.
+		_, err = l.Write([]byte("b"))
+		isNil(err, t)
+		isNil(l.Close(), t)
+		_, err = l.Write([]byte("c"))
+		isNil(err, t)
+		isNil(l.Reopen(), t)
+		_, err = l.Write([]byte("d"))
+		isNil(err, t)
+
+		got := ""
+		timeout := time.After(5 * time.Second)
+		for len(got) < 3 {
+			select {
+			case b := <-ch:
+				got += string(b)
+			case <-timeout:
+				t.Fatalf("timed out, got %q", got)
+			}
+		}
+		// nothing more than the three writes arrives.
+		select {
+		case b := <-ch:
+			got += string(b)
+		case <-time.After(50 * time.Millisecond):
+		}
+		equals("bcd", got, t)
+
+		cancel()
+		for range ch {
+		}
+		isNil(l.Close(), t)
+	}
+}
+
+// sameFileFS wraps an FS like one outside of this package would, telling files
+// apart with its own SameFile.
+type sameFileFS struct {
+	FS
+}
+
+func (fsys sameFileFS) SameFile(a, b os.FileInfo) bool {
+	return fsys.FS.(SameFiler).SameFile(a, b)
+}
@@ -0,0 +1,162 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/djherbis/times"
+)
+
+// File is an open file of an FS. *os.File implements it.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+	Sync() error
+	Chmod(mode os.FileMode) error
+}
+
+// FS is the file system a Logger keeps its log files on. Its methods behave
+// like the functions of the os package with the same name.
+type FS interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	MkdirAll(path string, perm os.FileMode) error
+	ReadDir(name string) ([]os.DirEntry, error)
+	Chtimes(name string, atime, mtime time.Time) error
+	Chmod(name string, mode os.FileMode) error
+
+	// Chown changes the owner and group of name. An ID of -1 is left
+	// unchanged. File systems without owners do nothing.
+	Chown(name string, uid, gid int) error
+
+	// BirthTime returns the creation time of name. ok is false if the file
+	// system does not record it.
+	BirthTime(name string) (t time.Time, ok bool, err error)
+}
+
+// SameFiler is implemented by the file systems that can tell whether two
+// os.FileInfo they returned describe the same file, like os.SameFile. Follow
+// relies on it to keep reading a file that the Logger reopens, such as after
+// Close or Reopen; on a file system without it, reopening the file may
+// deliver bytes written from then on twice.
+type SameFiler interface {
+	SameFile(a, b os.FileInfo) bool
+}
+
+// OSFS is the FS of the operating system, used by default.
+type OSFS struct{}
+
+// ensure we always implement FS and SameFiler
+var (
+	_ FS        = OSFS{}
+	_ SameFiler = OSFS{}
+)
+
+func (OSFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := osOpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (OSFS) Stat(name string) (os.FileInfo, error) {
+	return osStat(name)
+}
+
+func (OSFS) Rename(oldpath, newpath string) error {
+	return osRename(oldpath, newpath)
+}
+
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (OSFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (OSFS) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (OSFS) Chtimes(name string, atime, mtime time.Time) error {
+	return osChtimes(name, atime, mtime)
+}
+
+func (OSFS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (OSFS) Chown(name string, uid, gid int) error {
+	// this is a no-op anywhere but unix
+	return chownIDs(name, uid, gid)
+}
+
+func (OSFS) BirthTime(name string) (time.Time, bool, error) {
+	t, err := times.Stat(name)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if !t.HasBirthTime() {
+		return time.Time{}, false, nil
+	}
+	return t.BirthTime(), true, nil
+}
+
+func (OSFS) SameFile(a, b os.FileInfo) bool {
+	return os.SameFile(a, b)
+}
+
+// fs returns the FS of the Logger.
+func (l *Logger) fs() FS {
+	if l.FS != nil {
+		return l.FS
+	}
+	return OSFS{}
+}
+
+// open opens name for reading on fsys.
+func open(fsys FS, name string) (File, error) {
+	return fsys.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// readFile returns the content of name on fsys.
+func readFile(fsys FS, name string) ([]byte, error) {
+	f, err := open(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// writeFile writes data to name on fsys, creating or truncating it.
+func writeFile(fsys FS, name string, data []byte, perm os.FileMode) error {
+	f, err := fsys.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// sameFile reports whether a and b, returned by Stat on fsys or on its files,
+// describe the same file. It relies on the FS implementing SameFiler, and
+// falls back to os.SameFile.
+func sameFile(fsys FS, a, b os.FileInfo) bool {
+	if sf, ok := fsys.(SameFiler); ok {
+		return sf.SameFile(a, b)
+	}
+	return os.SameFile(a, b)
+}
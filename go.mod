@@ -5,7 +5,6 @@ go 1.21
 require (
 	github.com/BurntSushi/toml v1.5.0
 	github.com/djherbis/times v1.6.0
+	golang.org/x/sys v0.0.0-20220615213510-4f61da869c0c
 	gopkg.in/yaml.v2 v2.4.0
 )
-
-require golang.org/x/sys v0.0.0-20220615213510-4f61da869c0c // indirect
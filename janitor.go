@@ -0,0 +1,80 @@
+package logrotate
+
+import (
+	"time"
+)
+
+// janitor cleans up the old log files of a Logger every interval, so that
+// retention is enforced even when nothing is written.
+type janitor struct {
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// startJanitor starts the janitor if CleanupInterval is set, replacing one
+// running with another interval. The caller must hold l.mu.
+func (l *Logger) startJanitor() {
+	if l.janitor != nil {
+		if l.janitor.interval == l.CleanupInterval {
+			return
+		}
+		l.stopJanitor()
+	}
+	if l.CleanupInterval <= 0 {
+		return
+	}
+	j := &janitor{
+		interval: l.CleanupInterval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	l.janitor = j
+	go l.runJanitor(j, l.clock().NewTicker(j.interval))
+}
+
+// stopJanitor stops the janitor, if any, and returns a channel closed once
+// it has exited. The caller must hold l.mu, and release it before waiting on
+// the channel.
+func (l *Logger) stopJanitor() <-chan struct{} {
+	j := l.janitor
+	if j == nil {
+		return nil
+	}
+	l.janitor = nil
+	close(j.stop)
+	return j.done
+}
+
+// Cleanup compresses and removes old log files now, as after a rotation. It
+// waits for the cleanup running in the background, if any, to finish first,
+// so that once it returns old log files are as the CleanupInterval would
+// leave them at the current time of the Clock.
+func (l *Logger) Cleanup() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.dirExists() {
+		return nil
+	}
+	return l.millRun()
+}
+
+// runJanitor cleans up old log files on every tick until j is stopped.
+func (l *Logger) runJanitor(j *janitor, ticker Ticker) {
+	defer close(j.done)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-ticker.C():
+		}
+		l.mu.Lock()
+		// Holding l.mu keeps rotations out, and tells whether we were
+		// stopped meanwhile.
+		if l.janitor == j && l.dirExists() {
+			l.millRun()
+		}
+		l.mu.Unlock()
+	}
+}
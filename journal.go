@@ -0,0 +1,84 @@
+package logrotate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// journalSuffix is appended to the log file name to name the rotation journal.
+const journalSuffix = ".journal"
+
+// rotationJournal records a rotation in progress: the log file From is
+// being renamed to To at Time.
+type rotationJournal struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Time time.Time `json:"time"`
+}
+
+// journalPath returns the path of the rotation journal of the Logger.
+func (l *Logger) journalPath() string {
+	return l.filename() + journalSuffix
+}
+
+// writeJournal durably records j before the rotation it describes starts.
+func (l *Logger) writeJournal(j rotationJournal) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("can't encode rotation journal: %s", err)
+	}
+	f, err := l.fs().OpenFile(l.journalPath(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("can't write rotation journal: %s", err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return fmt.Errorf("can't write rotation journal: %s", err)
+	}
+	syncDir(l.fs(), l.dir())
+	return nil
+}
+
+// removeJournal removes the rotation journal once the rotation is complete.
+func (l *Logger) removeJournal() {
+	l.fs().Remove(l.journalPath())
+}
+
+// recoverRotation completes or undoes a rotation interrupted by a crash, as
+// recorded in the rotation journal. If the log file was renamed, the backup
+// gets the times and manifest entry it would have had; the missing active
+// file is then created as usual. If it was not, nothing has changed and the
+// rotation is forgotten.
+func (l *Logger) recoverRotation() error {
+	data, err := readFile(l.fs(), l.journalPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't read rotation journal: %s", err)
+	}
+	var j rotationJournal
+	if err := json.Unmarshal(data, &j); err != nil {
+		// A journal that was not completely written means the
+		// rotation had not started yet.
+		l.removeJournal()
+		return nil
+	}
+
+	if _, err := l.fs().Stat(j.To); err == nil && filepath.Dir(j.To) == l.dir() {
+		if err := l.finishBackup(j.To, j.Time); err != nil {
+			return err
+		}
+	}
+	l.removeJournal()
+	return nil
+}
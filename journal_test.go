@@ -0,0 +1,108 @@
+package logrotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotationJournalRecovery(t *testing.T) {
+	errInjected := errors.New("injected failure")
+	tests := []struct {
+		name string
+		// inject makes one step of the rotation fail, as if the process
+		// crashed there, and returns a function undoing it.
+		inject func() func()
+		// renamed reports whether the rename happened before the crash.
+		renamed bool
+	}{
+		{
+			name: "journal",
+			inject: func() func() {
+				osOpenFile = func(name string, flag int, perm os.FileMode) (*os.File, error) {
+					return nil, errInjected
+				}
+				return func() { osOpenFile = os.OpenFile }
+			},
+		},
+		{
+			name: "rename",
+			inject: func() func() {
+				osRename = func(string, string) error { return errInjected }
+				return func() { osRename = os.Rename }
+			},
+		},
+		{
+			name: "chtimes",
+			inject: func() func() {
+				osChtimes = func(string, time.Time, time.Time) error { return errInjected }
+				return func() { osChtimes = os.Chtimes }
+			},
+			renamed: true,
+		},
+		{
+			name: "open",
+			inject: func() func() {
+				osOpenFile = func(name string, flag int, perm os.FileMode) (*os.File, error) {
+					if filepath.Base(name) == filepath.Base(logFile("")) {
+						return nil, errInjected
+					}
+					return os.OpenFile(name, flag, perm)
+				}
+				return func() { osOpenFile = os.OpenFile }
+			},
+			renamed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currentTime = fakeTime
+			dir := makeTempDir(identifier(t), t)
+			defer os.RemoveAll(dir)
+
+			filename := logFile(dir)
+			b := []byte("boo!")
+			isNil(os.WriteFile(filename, b, 0644), t)
+
+			l := &Logger{
+				Filename: filename,
+				Manifest: true,
+			}
+			defer l.Close()
+
+			restore := tt.inject()
+			err := l.Rotate()
+			restore()
+			notNil(err, t)
+
+			// Rotation time is recorded before the crash, recovery
+			// happens later.
+			rotatedAt := fakeTime()
+			newFakeTime()
+
+			b2 := []byte("foo!")
+			_, err = l.Write(b2)
+			isNil(err, t)
+			notExist(filename+journalSuffix, t)
+
+			backup := backupFileWithOrder(dir, 1)
+			if !tt.renamed {
+				existsWithContent(filename, append(b, b2...), t)
+				notExist(backup, t)
+				return
+			}
+			existsWithContent(filename, b2, t)
+			existsWithContent(backup, b, t)
+			info, err := os.Stat(backup)
+			isNil(err, t)
+			assert(info.ModTime().Equal(rotatedAt), t, "expected backup time %v, got %v", rotatedAt, info.ModTime())
+			m, err := l.loadManifest()
+			isNil(err, t)
+			equals(1, len(m.Backups), t)
+			assert(m.Backups[0].RotatedAt.Equal(rotatedAt), t, "expected rotation time %v, got %v", rotatedAt, m.Backups[0].RotatedAt)
+		})
+	}
+}
@@ -4,8 +4,10 @@ package logrotate
 
 import (
 	"compress/gzip"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -14,12 +16,11 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/djherbis/times"
 )
 
 const (
 	compressSuffix = ".gz"
+	tmpSuffix      = ".tmp"
 	defaultMaxSize = 100
 )
 
@@ -85,10 +86,22 @@ type Logger struct {
 	// MaxAge is the maximum number of days to retain old log files based on the
 	// timestamp encoded in their filename.  Note that a day is defined as 24
 	// hours and may not exactly correspond to calendar days due to daylight
-	// savings, leap seconds, etc. The default is not to remove old log files
-	// based on age.
+	// savings, leap seconds, etc, unless CalendarDays is set. The default is
+	// not to remove old log files based on age.
 	MaxAge int `json:"maxage" yaml:"maxage"`
 
+	// MaxAgeDuration is the maximum age of old log files, for ages that are
+	// not a whole number of days, such as a few hours for high-volume debug
+	// logs. It takes precedence over MaxAge.
+	MaxAgeDuration time.Duration `json:"maxageduration" yaml:"maxageduration"`
+
+	// CalendarDays determines if MaxAge counts calendar days in the time zone
+	// of the Logger, which are 23 or 25 hours long when daylight saving time
+	// starts or ends, rather than periods of 24 hours. Old log files are then
+	// kept for MaxAge days after the day they were rotated on: with a MaxAge
+	// of 1, a log file rotated on Monday is removed once Wednesday starts.
+	CalendarDays bool `json:"calendardays" yaml:"calendardays"`
+
 	// MaxBackups is the maximum number of old log files to retain.  The default
 	// is to retain all old log files (though MaxAge may still cause them to get
 	// deleted.)
@@ -97,24 +110,142 @@ type Logger struct {
 	// LocalTime determines if the time used for formatting the timestamps in
 	// backup files is the computer's local time.  The default is to use UTC
 	// time.
+	//
+	// Deprecated: set TimeZone to "Local" instead.
 	LocalTime bool `json:"localtime" yaml:"localtime"`
 
+	// TimeZone is the name of the time zone, such as "Europe/Paris" or
+	// "Local", that the timestamps in the names of backups are written in
+	// and that calendar days are counted in. The default is UTC.
+	TimeZone string `json:"timezone" yaml:"timezone"`
+
+	// Location is the time zone of the Logger, in place of TimeZone, for
+	// time zones that are not loaded by name.
+	Location *time.Location `json:"-" yaml:"-" toml:"-"`
+
 	// Compress determines if the rotated log files should be compressed
 	// using gzip. The default is not to perform compression.
 	Compress bool `json:"compress" yaml:"compress"`
 
+	// FileMode is the permission of new log files. By default they get the
+	// mode of the rotated log file, or 0600 if there is none.
+	FileMode os.FileMode `json:"filemode" yaml:"filemode"`
+
+	// BackupMode is the permission log files get when they are rotated, such
+	// as 0440 to make backups read-only. Compressed and encrypted backups
+	// keep it. By default backups keep the mode of the log file.
+	BackupMode os.FileMode `json:"backupmode" yaml:"backupmode"`
+
+	// DirMode is the permission of the directories created for the log
+	// file. It defaults to 0755.
+	DirMode os.FileMode `json:"dirmode" yaml:"dirmode"`
+
+	// Owner and Group are the user and group, by name or numeric ID, that
+	// new log files and backups belong to. Together with FileMode and
+	// BackupMode they allow, for example, a log reader group to read the
+	// backups but not the live file. By default new log files belong to the
+	// owner and group of the rotated log file. They are ignored on Windows.
+	Owner string `json:"owner" yaml:"owner"`
+	Group string `json:"group" yaml:"group"`
+
+	// Manifest determines if a manifest of the backups is kept next to the
+	// log file, in `<filename>.manifest.json`. It records the rotation time,
+	// sizes, checksum and order of every backup. The recorded rotation time
+	// is then used to sort and expire backups, instead of the birth time of
+	// the file which compression or copying to another host resets.
+	Manifest bool `json:"manifest" yaml:"manifest"`
+
+	// Checksum determines if backups are chained in the manifest for tamper
+	// evidence: the entry of every backup includes the checksum of the
+	// previous one, and removals are recorded by appending entries as well.
+	// Verify checks the chain. It implies Manifest.
+	//
+	// The checksum of a backup is computed during the rotation, which holds
+	// up writes for as long as reading the whole file takes. Entries are
+	// never dropped, so the manifest grows by two entries per backup for the
+	// life of the log file.
+	Checksum bool `json:"checksum" yaml:"checksum"`
+
+	// RotateOnOpen determines if an existing, non-empty log file is rotated
+	// when the Logger first opens it, so that every run of the process
+	// starts a fresh file. Later reopenings, such as after Close, append as
+	// usual.
+	RotateOnOpen bool `json:"rotateonopen" yaml:"rotateonopen"`
+
+	// MaxFileAge is the maximum age of the log file before it gets rotated,
+	// even if it is smaller than MaxBytes. The age is measured from the birth
+	// time of the file, or from when it was opened where the file system
+	// does not record it or a Clock is set. It is checked on open and on every write. The
+	// default is not to rotate on age.
+	MaxFileAge time.Duration `json:"maxfileage" yaml:"maxfileage"`
+
+	// CleanupInterval, if set, makes the Logger compress and remove old log
+	// files every interval in the background, and not only after rotations,
+	// so that MaxAge is enforced even when little or nothing is written. It
+	// starts when the log file is opened and stops on Close.
+	CleanupInterval time.Duration `json:"cleanupinterval" yaml:"cleanupinterval"`
+
+	// SigningKey, if set, is used to sign the manifest entry of every backup
+	// when Checksum is set.
+	SigningKey ed25519.PrivateKey `json:"-" yaml:"-" toml:"-"`
+
+	// VerifyKey is the key Verify checks signatures with. It defaults to the
+	// public key of SigningKey.
+	VerifyKey ed25519.PublicKey `json:"-" yaml:"-" toml:"-"`
+
+	// Encrypter, if set, encrypts the backups at rest, after they are
+	// compressed. Encrypted backups get the `.enc` suffix. If it also
+	// implements Decrypter, encrypted backups are verified before the
+	// original is removed, and can be read back with NewReader.
+	Encrypter Encrypter `json:"-" yaml:"-" toml:"-"`
+
+	// FS is the file system the log files are kept on. It defaults to the
+	// file system of the operating system.
+	FS FS `json:"-" yaml:"-" toml:"-"`
+
+	// Clock is the source of time of the Logger. It defaults to the system
+	// clock. Giving every Logger its own Clock lets tests control time
+	// without sharing it with other Loggers.
+	Clock Clock `json:"-" yaml:"-" toml:"-"`
+
+	// Retention, if set, decides which backups are kept, compressed and
+	// removed, in place of MaxBackups, MaxAge and Compress. GFSPolicy keeps
+	// backups in tiers, such as one per day for a month.
+	Retention RetentionPolicy `json:"-" yaml:"-" toml:"-"`
+
 	size int64
-	file *os.File
-	mu   sync.Mutex
+	file File
+	// born is the birth time of the current file.
+	born time.Time
+	// opened reports whether the Logger has opened a file before.
+	opened bool
+	// orderScanned reports whether FileOrder was raised to the highest order
+	// of the existing backups.
+	orderScanned bool
+	// idle reports whether the file was closed by a Router for being idle.
+	idle      bool
+	mu        sync.Mutex
+	followers []*follower
+	janitor   *janitor
+	zone      *zone
+	// scratch is reused by WriteBatch to coalesce small messages.
+	scratch []byte
 }
 
 var (
-	// currentTime exists so it can be mocked out by tests.
+	// currentTime exists so it can be mocked out by tests. It is the time of
+	// Loggers without a Clock.
 	currentTime = time.Now
 
 	// os_Stat exists so it can be mocked out by tests.
 	osStat = os.Stat
 
+	// osRename, osChtimes and osOpenFile exist so that failures can be
+	// injected by tests using the OSFS.
+	osRename   = os.Rename
+	osChtimes  = os.Chtimes
+	osOpenFile = os.OpenFile
+
 	// megabyte is the conversion factor between MaxSize and bytes.  It is a
 	// variable so tests can mock it out and not need to write megabytes of data
 	// to disk.
@@ -141,7 +272,7 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 		}
 	}
 
-	if l.size+writeLen > l.max(writeLen) {
+	if l.size+writeLen > l.max(writeLen) || l.stale() {
 		if err := l.rotate(); err != nil {
 			return 0, err
 		}
@@ -149,15 +280,22 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 
 	n, err = l.file.Write(p)
 	l.size += int64(n)
+	l.signalFollowers()
 
 	return n, err
 }
 
-// Close implements io.Closer, and closes the current logfile.
+// Close implements io.Closer, and closes the current logfile. It also stops
+// the background cleanup, which restarts with the next write.
 func (l *Logger) Close() error {
 	l.mu.Lock()
-	defer l.mu.Unlock()
-	return l.close()
+	err := l.close()
+	done := l.stopJanitor()
+	l.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	return err
 }
 
 // close closes the file if it is open.
@@ -181,6 +319,53 @@ func (l *Logger) Rotate() error {
 	return l.rotate()
 }
 
+// Reopen closes the current log file and opens Filename again by path,
+// without renaming anything. It is meant for when an external tool such as
+// logrotate(8) has already moved the log file aside: following writes go to a
+// file at the original path, which is created if it does not exist. The size
+// of the reopened file is read again so that rotation keeps working on it.
+// Reopen is safe to call concurrently with Write.
+func (l *Logger) Reopen() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.close(); err != nil {
+		return err
+	}
+	return l.reopen()
+}
+
+// reopen opens the logfile for appending like openExistingOrNew, but reports
+// a failure to open an existing file instead of moving it out of the way.
+func (l *Logger) reopen() error {
+	l.millRun()
+	l.opened = true
+
+	filename := l.filename()
+	_, err := l.fs().Stat(filename)
+	if os.IsNotExist(err) {
+		return l.openNew()
+	}
+	if err != nil {
+		return fmt.Errorf("error getting log file info: %s", err)
+	}
+
+	file, err := l.fs().OpenFile(filename, os.O_APPEND|os.O_WRONLY, l.fileMode(0644))
+	if err != nil {
+		return fmt.Errorf("can't reopen logfile: %s", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error getting log file info: %s", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	l.born = l.birthTime(filename)
+	l.notifyFollowers()
+	l.startJanitor()
+	return nil
+}
+
 // rotate closes the current file, moves it aside with either a timestamp
 // in the name or number at the end of the name, (if it exists),
 // opens a new file with the original filename, and then runs post-rotation processing and removal.
@@ -200,36 +385,40 @@ func (l *Logger) rotate() error {
 // openNew opens a new log file for writing, moving any old log file out of the
 // way.  This methods assumes the file has already been closed.
 func (l *Logger) openNew() error {
-	err := os.MkdirAll(l.dir(), 0755)
+	err := l.fs().MkdirAll(l.dir(), l.dirMode())
 	if err != nil {
 		return fmt.Errorf("can't make directories for new logfile: %s", err)
 	}
 
 	name := l.filename()
 	mode := os.FileMode(0600)
-	info, err := osStat(name)
+	journaled := false
+	info, err := l.fs().Stat(name)
 	if err == nil {
 		// Copy the mode off the old logfile.
 		mode = info.Mode()
 		// move the existing file
-		newname, err := l.backupName(name, l.FilenameTimeFormat, l.LocalTime)
+		newname, err := l.backupName(name, l.FilenameTimeFormat)
 		if err != nil {
 			return err
 		}
-		if err := os.Rename(name, newname); err != nil {
+		// Record the rename first, so that it can be completed or undone
+		// if we crash before the new log file is open.
+		rotatedAt := l.now()
+		if err := l.writeJournal(rotationJournal{From: name, To: newname, Time: rotatedAt}); err != nil {
+			return err
+		}
+		journaled = true
+		if err := l.fs().Rename(name, newname); err != nil {
+			// nothing has changed, so there is nothing to recover.
+			l.removeJournal()
 			return fmt.Errorf("can't rename log file: %s", err)
 		}
-
-		// Set both access time and modified time of the backup file to the current time
-		// We will use the file Mod time to get time informations of backup file with standard name format
-		if l.FilenameTimeFormat == "" {
-			err := os.Chtimes(newname, currentTime(), currentTime())
-			if err != nil {
-				return err
-			}
+		if err := l.finishBackup(newname, rotatedAt); err != nil {
+			return err
 		}
 		// this is a no-op anywhere but linux
-		if err := chown(name, info); err != nil {
+		if err := chown(l.fs(), name, info); err != nil {
 			return err
 		}
 	}
@@ -237,30 +426,82 @@ func (l *Logger) openNew() error {
 	// we use truncate here because this should only get called when we've moved
 	// the file ourselves. if someone else creates the file in the meantime,
 	// just wipe out the contents.
-	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	f, err := l.fs().OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, l.fileMode(mode))
 	if err != nil {
 		return fmt.Errorf("can't open new logfile: %s", err)
 	}
+	if l.FileMode != 0 {
+		// The file may already exist with another mode, and the umask
+		// applies to new ones.
+		if err := f.Chmod(l.FileMode); err != nil {
+			f.Close()
+			return fmt.Errorf("can't chmod new logfile: %s", err)
+		}
+	}
+	if err := l.applyOwnership(name); err != nil {
+		f.Close()
+		return err
+	}
+	if journaled {
+		l.removeJournal()
+	}
 
 	l.file = f
 	l.size = 0
+	l.born = l.now()
+	l.notifyFollowers()
+	l.startJanitor()
+	return nil
+}
+
+// finishBackup completes the rotation of a log file renamed to newname at
+// rotatedAt.
+func (l *Logger) finishBackup(newname string, rotatedAt time.Time) error {
+	// Set both access time and modified time of the backup file to the rotation time
+	// We will use the file Mod time to get time informations of backup file with standard name format
+	if l.FilenameTimeFormat == "" {
+		err := l.fs().Chtimes(newname, rotatedAt, rotatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	if l.BackupMode != 0 {
+		if err := l.fs().Chmod(newname, l.BackupMode); err != nil {
+			return fmt.Errorf("can't chmod backup file: %s", err)
+		}
+	}
+	if err := l.applyOwnership(newname); err != nil {
+		return err
+	}
+	if l.manifestEnabled() {
+		if err := l.recordBackup(newname, rotatedAt); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // backupName creates a new filename
-func (l *Logger) backupName(name, nameTimeFormat string, local bool) (string, error) {
+func (l *Logger) backupName(name, nameTimeFormat string) (string, error) {
 	dir := filepath.Dir(name)
 	prefix, ext := l.prefixAndExt()
 	var filename string
 	if nameTimeFormat != "" {
-		t := currentTime()
-		if !local {
-			t = t.UTC()
+		loc, err := l.location()
+		if err != nil {
+			return "", err
 		}
-		timestamp := t.Format(nameTimeFormat)
+		timestamp := l.now().In(loc).Format(nameTimeFormat)
 		filename = fmt.Sprintf("%s%s%s", prefix, timestamp, ext)
 	} else {
+		highest, err := l.highestOrder()
+		if err != nil {
+			return "", err
+		}
 		mutex.Lock()
+		if highest > l.FileOrder {
+			l.FileOrder = highest
+		}
 		l.FileOrder += 1
 		filename = fmt.Sprintf("%s%s.%d", prefix, ext, l.FileOrder)
 		mutex.Unlock()
@@ -269,21 +510,67 @@ func (l *Logger) backupName(name, nameTimeFormat string, local bool) (string, er
 	return filepath.Join(dir, filename), nil
 }
 
+// highestOrder returns the highest order of the existing backups the first
+// time it is called, and 0 afterwards, so that a new process continues the
+// order of the backups of the previous one instead of renaming the log file
+// onto them.
+func (l *Logger) highestOrder() (int, error) {
+	if l.orderScanned {
+		return 0, nil
+	}
+	files, err := l.fs().ReadDir(l.dir())
+	if err != nil {
+		return 0, fmt.Errorf("can't read log file directory: %s", err)
+	}
+	prefix, ext := l.prefixAndExt()
+	highest := 0
+	for _, f := range files {
+		for _, suffix := range backupSuffixes {
+			if order, err := l.orderFromName(f.Name(), prefix, ext+suffix); err == nil {
+				if order > highest {
+					highest = order
+				}
+				break
+			}
+		}
+	}
+	l.orderScanned = true
+	return highest, nil
+}
+
 // openExistingOrNew opens the logfile if it exists.
 // If there is no such file a new file is created.
 func (l *Logger) openExistingOrNew() error {
-	l.millRun()
+	// A file closed by a Router for being idle was already recovered and
+	// cleaned up around when it was first opened.
+	if !l.idle {
+		l.recoverRotation()
+		l.reconcile()
+		l.millRun()
+	}
+	l.idle = false
+
+	first := !l.opened
+	l.opened = true
 
 	filename := l.filename()
-	info, err := osStat(filename)
+	info, err := l.fs().Stat(filename)
 	if os.IsNotExist(err) {
 		return l.openNew()
 	}
 	if err != nil {
 		return fmt.Errorf("error getting log file info: %s", err)
 	}
+	if info.Size() > 0 {
+		if first && l.RotateOnOpen {
+			return l.rotate()
+		}
+		if l.MaxFileAge > 0 && l.now().Sub(l.birthTime(filename)) >= l.MaxFileAge {
+			return l.rotate()
+		}
+	}
 
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := l.fs().OpenFile(filename, os.O_APPEND|os.O_WRONLY, l.fileMode(0644))
 	if err != nil {
 		// if we fail to open the old log file for some reason, just ignore
 		// it and open a new log file.
@@ -291,9 +578,32 @@ func (l *Logger) openExistingOrNew() error {
 	}
 	l.file = file
 	l.size = info.Size()
+	l.born = l.birthTime(filename)
+	l.notifyFollowers()
+	l.startJanitor()
 	return nil
 }
 
+// birthTime returns the birth time of the log file name, or the current time
+// if the file system does not record it or the Logger has a Clock.
+func (l *Logger) birthTime(name string) time.Time {
+	// The birth time of the file comes from the system clock, and can't be
+	// compared with the time of another Clock.
+	if l.Clock != nil {
+		return l.now()
+	}
+	if t, ok, err := l.fs().BirthTime(name); err == nil && ok {
+		return t
+	}
+	return l.now()
+}
+
+// stale reports whether the current file is older than MaxFileAge and not
+// empty.
+func (l *Logger) stale() bool {
+	return l.MaxFileAge > 0 && l.size > 0 && l.now().Sub(l.born) >= l.MaxFileAge
+}
+
 // filename generates the name of the logfile.
 func (l *Logger) filename() string {
 	if l.Filename != "" {
@@ -308,7 +618,7 @@ func (l *Logger) filename() string {
 // files are removed, keeping at most l.MaxBackups files, as long as
 // none of them are older than MaxAge.
 func (l *Logger) millRun() error {
-	if l.MaxBackups == 0 && l.MaxAge == 0 && !l.Compress {
+	if l.Retention == nil && l.MaxBackups == 0 && l.maxAge() == 0 && !l.Compress && l.Encrypter == nil {
 		return nil
 	}
 
@@ -316,74 +626,120 @@ func (l *Logger) millRun() error {
 	if err != nil {
 		return err
 	}
-	var compress, remove []logInfo
-
-	if l.MaxBackups > 0 && l.MaxBackups < len(files) {
-		preserved := make(map[string]bool)
-		var remaining []logInfo
-		for _, f := range files {
-			// Only count the uncompressed log file or the
-			// compressed log file, not both.
-			fn := strings.TrimSuffix(f.Name(), compressSuffix)
-			preserved[fn] = true
-
-			if len(preserved) > l.MaxBackups {
-				remove = append(remove, f)
-			} else {
-				remaining = append(remaining, f)
-			}
-		}
-		files = remaining
-	}
-	if l.MaxAge > 0 {
-		diff := time.Duration(int64(24*time.Hour) * int64(l.MaxAge))
-		cutoff := currentTime().Add(-1 * diff)
-
-		var remaining []logInfo
-		for _, f := range files {
-			if f.timestamp.Before(cutoff) {
-				remove = append(remove, f)
-			} else {
-				remaining = append(remaining, f)
-			}
-		}
-		files = remaining
-	}
-
-	if l.Compress {
-		for _, f := range files {
-			if !strings.HasSuffix(f.Name(), compressSuffix) {
-				compress = append(compress, f)
-			}
-		}
+	compress, encrypt, remove, err := l.millPlan(files)
+	if err != nil {
+		return err
 	}
 
+	var removed []string
+	// renamed maps the old log files compressed or encrypted to their new name.
+	renamed := make(map[string]string)
 	for _, f := range remove {
-		errRemove := os.Remove(filepath.Join(l.dir(), f.Name()))
+		errRemove := l.fs().Remove(filepath.Join(l.dir(), f.Name()))
 		if err == nil && errRemove != nil {
 			err = errRemove
 		}
+		if errRemove == nil {
+			removed = append(removed, f.Name())
+		}
 	}
 	for _, f := range compress {
 		fn := filepath.Join(l.dir(), f.Name())
-		errCompress := compressLogFile(fn, fn+compressSuffix)
+		errCompress := compressLogFile(l.fs(), fn, fn+compressSuffix)
 		if err == nil && errCompress != nil {
 			err = errCompress
 		}
+		if errCompress == nil {
+			renamed[f.Name()] = f.Name() + compressSuffix
+		}
+	}
+	for _, f := range encrypt {
+		name := f.Name()
+		if newName, ok := renamed[name]; ok {
+			name = newName
+		}
+		fn := filepath.Join(l.dir(), name)
+		errEncrypt := encryptLogFile(l.fs(), fn, fn+encryptSuffix, l.Encrypter)
+		if err == nil && errEncrypt != nil {
+			err = errEncrypt
+		}
+		if errEncrypt == nil {
+			renamed[f.Name()] = name + encryptSuffix
+		}
+	}
+
+	if l.manifestEnabled() {
+		errManifest := l.updateManifest(renamed, removed)
+		if err == nil && errManifest != nil {
+			err = errManifest
+		}
 	}
 
 	return err
 }
 
+// millPlan returns the old log files millRun compresses, encrypts and
+// removes, given all the old log files sorted by bTime, as decided by the
+// RetentionPolicy. Pinned files are left out of the RetentionPolicy. Files
+// are encrypted after being compressed.
+func (l *Logger) millPlan(files []logInfo) (compress, encrypt, remove []logInfo, err error) {
+	pins, err := l.loadPins()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	byName := make(map[string]logInfo, len(files))
+	var unpinned []logInfo
+	for _, f := range files {
+		if pins[baseBackupName(f.Name())] {
+			continue
+		}
+		byName[f.Name()] = f
+		unpinned = append(unpinned, f)
+	}
+	policy, err := l.retention()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	plan := policy.Plan(l.backupInfos(unpinned), l.now())
+
+	removed := make(map[string]bool)
+	for _, b := range plan.Remove {
+		if f, ok := byName[b.Name]; ok && !removed[b.Name] {
+			removed[b.Name] = true
+			remove = append(remove, f)
+		}
+	}
+	for _, b := range plan.Compress {
+		f, ok := byName[b.Name]
+		if ok && !removed[b.Name] && !isCompressed(b.Name) && !isEncrypted(b.Name) {
+			compress = append(compress, f)
+		}
+	}
+	if l.Encrypter != nil {
+		for _, f := range files {
+			if !removed[f.Name()] && !isEncrypted(f.Name()) {
+				encrypt = append(encrypt, f)
+			}
+		}
+	}
+
+	return compress, encrypt, remove, nil
+}
+
 // oldLogFiles returns the list of backup log files stored in the same
 // directory as the current log file, sorted by bTime
 func (l *Logger) oldLogFiles() ([]logInfo, error) {
-	files, err := os.ReadDir(l.dir())
+	files, err := l.fs().ReadDir(l.dir())
 	if err != nil {
 		return nil, fmt.Errorf("can't read log file directory: %s", err)
 	}
 	logFiles := []logInfo{}
 
+	// Without the time zone, backups named after their rotation time would
+	// all look like foreign files and escape retention.
+	if _, err := l.location(); err != nil {
+		return nil, err
+	}
 	prefix, ext := l.prefixAndExt()
 
 	for _, f := range files {
@@ -394,35 +750,39 @@ func (l *Logger) oldLogFiles() ([]logInfo, error) {
 		if err != nil {
 			return nil, err
 		}
-		switch {
-		case l.FilenameTimeFormat != "":
-			if t, err := l.timeFromName(f.Name(), prefix, ext); err == nil {
-				logFiles = append(logFiles, logInfo{t, fInfo})
-				continue
-			}
-			if t, err := l.timeFromName(f.Name(), prefix, ext+compressSuffix); err == nil {
-				logFiles = append(logFiles, logInfo{t, fInfo})
-				continue
-			}
-		default:
-			if _, err := l.orderFromName(f.Name(), prefix, ext); err == nil {
-				logInfoTime, err := l.getFileTimeInfo(f.Name())
+		for _, suffix := range backupSuffixes {
+			if l.FilenameTimeFormat != "" {
+				t, err := l.timeFromName(f.Name(), prefix, ext+suffix)
 				if err != nil {
-					return nil, err
+					continue
 				}
-				logFiles = append(logFiles, logInfo{logInfoTime, fInfo})
-				continue
+				logFiles = append(logFiles, logInfo{t, true, fInfo})
+				break
 			}
-			if _, err := l.orderFromName(f.Name(), prefix, ext+compressSuffix); err == nil {
-				logInfoTime, err := l.getFileTimeInfo(f.Name())
-				if err != nil {
-					return nil, err
-				}
-				logFiles = append(logFiles, logInfo{logInfoTime, fInfo})
+			if _, err := l.orderFromName(f.Name(), prefix, ext+suffix); err != nil {
 				continue
 			}
+			logInfoTime, err := l.getFileTimeInfo(f.Name())
+			if err != nil {
+				return nil, err
+			}
+			logFiles = append(logFiles, logInfo{logInfoTime, false, fInfo})
+			break
 		}
+	}
 
+	if l.manifestEnabled() {
+		// Prefer the recorded rotation time when there is one.
+		m, err := l.loadManifest()
+		if err != nil {
+			return nil, err
+		}
+		for i := range logFiles {
+			if e := m.find(logFiles[i].Name()); e != nil {
+				logFiles[i].timestamp = e.RotatedAt
+				logFiles[i].rotated = true
+			}
+		}
 	}
 	sort.Sort(byBirthTime(logFiles))
 
@@ -440,7 +800,11 @@ func (l *Logger) timeFromName(filename, prefix, ext string) (time.Time, error) {
 		return time.Time{}, errors.New("mismatched extension")
 	}
 	ts := filename[len(prefix) : len(filename)-len(ext)]
-	return time.Parse(l.FilenameTimeFormat, ts)
+	loc, err := l.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(l.FilenameTimeFormat, ts, loc)
 }
 
 // orderFromName extracts the order from the filename
@@ -470,18 +834,25 @@ func (l *Logger) orderFromName(filename string, prefix, ext string) (int, error)
 
 // retrieve file time informations
 func (l *Logger) getFileTimeInfo(fileName string) (time.Time, error) {
-	t, err := times.Stat(filepath.Join(l.dir(), fileName))
+	path := filepath.Join(l.dir(), fileName)
+	// The birth time of the file comes from the system clock, and can't be
+	// compared with the time of another Clock.
+	if l.Clock == nil {
+		birth, ok, err := l.fs().BirthTime(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ok {
+			return birth, nil
+		}
+	}
+	// we use the modification time, which is set to the rotation time and
+	// fix during the log file life
+	info, err := l.fs().Stat(path)
 	if err != nil {
 		return time.Time{}, err
 	}
-	var logInfoTime time.Time
-	if t.HasBirthTime() {
-		logInfoTime = t.BirthTime()
-	} else {
-		// we use change time which is fix during the log file life
-		logInfoTime = t.ModTime()
-	}
-	return logInfoTime, nil
+	return info.ModTime(), nil
 }
 
 // max returns the maximum size in bytes of log files before rolling.
@@ -520,53 +891,96 @@ func (l *Logger) prefixAndExt() (string, string) {
 
 // compressLogFile compresses the given log file, removing the
 // uncompressed log file if successful.
-func compressLogFile(src, dst string) (err error) {
-	f, err := os.Open(src)
+func compressLogFile(fsys FS, src, dst string) error {
+	newWriter := func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	}
+	verify := func(path string, size int64, sum uint32) error {
+		return verifyCompressed(fsys, path, size, sum)
+	}
+	if err := transformLogFile(fsys, src, dst, newWriter, verify); err != nil {
+		return fmt.Errorf("failed to compress log file: %v", err)
+	}
+	return nil
+}
+
+// transformLogFile writes the given log file to dst through the writer
+// returned by newWriter, removing the original log file if successful. The
+// data is written to a temporary file, synced and checked with verify, which
+// may be nil, before it is renamed to dst, so that a crash never leaves a
+// truncated dst next to src.
+func transformLogFile(fsys FS, src, dst string, newWriter func(io.Writer) (io.WriteCloser, error), verify func(path string, size int64, sum uint32) error) (err error) {
+	f, err := open(fsys, src)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %v", err)
 	}
 	defer f.Close()
 
-	fi, err := osStat(src)
+	fi, err := fsys.Stat(src)
 	if err != nil {
 		return fmt.Errorf("failed to stat log file: %v", err)
 	}
 
-	if err := chown(dst, fi); err != nil {
-		return fmt.Errorf("failed to chown compressed log file: %v", err)
-	}
-
 	// If this file already exists, we presume it was created by
-	// a previous attempt to compress the log file.
-	gzf, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	// a previous attempt to transform the log file. It only gets the mode
+	// of the log file once written, which may be read-only.
+	tmp := dst + tmpSuffix
+	out, err := fsys.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
 	if err != nil {
-		return fmt.Errorf("failed to open compressed log file: %v", err)
+		return fmt.Errorf("failed to open log file: %v", err)
 	}
-	defer gzf.Close()
-
-	gz := gzip.NewWriter(gzf)
+	defer out.Close()
 
 	defer func() {
 		if err != nil {
-			os.Remove(dst)
-			err = fmt.Errorf("failed to compress log file: %v", err)
+			fsys.Remove(tmp)
 		}
 	}()
 
-	if _, err := io.Copy(gz, f); err != nil {
+	if err := chown(fsys, tmp, fi); err != nil {
+		return fmt.Errorf("failed to chown log file: %v", err)
+	}
+
+	w, err := newWriter(out)
+	if err != nil {
+		return err
+	}
+	crc := crc32.NewIEEE()
+	n, err := io.Copy(io.MultiWriter(w, crc), f)
+	if err != nil {
 		return err
 	}
-	if err := gz.Close(); err != nil {
+	if err := w.Close(); err != nil {
 		return err
 	}
-	if err := gzf.Close(); err != nil {
+	if err := out.Chmod(fi.Mode()); err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// Keep the modification time, which is the rotation time of backups
+	// with the standard file name format.
+	if err := fsys.Chtimes(tmp, fi.ModTime(), fi.ModTime()); err != nil {
+		return err
+	}
+	if verify != nil {
+		if err := verify(tmp, n, crc.Sum32()); err != nil {
+			return err
+		}
+	}
+	if err := fsys.Rename(tmp, dst); err != nil {
 		return err
 	}
+	syncDir(fsys, filepath.Dir(dst))
 
 	if err := f.Close(); err != nil {
 		return err
 	}
-	if err := os.Remove(src); err != nil {
+	if err := fsys.Remove(src); err != nil {
 		return err
 	}
 
@@ -577,6 +991,9 @@ func compressLogFile(src, dst string) (err error) {
 // timestamp.
 type logInfo struct {
 	timestamp time.Time
+	// rotated reports whether timestamp is the rotation time, from the name
+	// or the manifest, rather than a time of the file.
+	rotated bool
 	os.FileInfo
 }
 
@@ -877,6 +877,97 @@ func TestRotateBackupsWithOrder(t *testing.T) {
 	existsWithContent(filename, b2, t)
 }
 
+func TestReopen(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReopen", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		MaxBytes: 10,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	// An external tool moves the file aside and creates a new one.
+	moved := filename + ".moved"
+	err = os.Rename(filename, moved)
+	isNil(err, t)
+	start := []byte("started")
+	err = os.WriteFile(filename, start, 0644)
+	isNil(err, t)
+
+	err = l.Reopen()
+	isNil(err, t)
+	equals(int64(len(start)), l.size, t)
+
+	// Nothing has been renamed, and the refreshed size makes this write rotate.
+	b2 := []byte("foooo!")
+	_, err = l.Write(b2)
+	isNil(err, t)
+	existsWithContent(moved, b, t)
+	existsWithContent(filename, b2, t)
+	existsWithContent(backupFileWithOrder(dir, 1), start, t)
+	fileCount(dir, 3, t)
+}
+
+func TestReopenMissingFile(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReopenMissingFile", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	err = os.Remove(filename)
+	isNil(err, t)
+	err = l.Reopen()
+	isNil(err, t)
+
+	b2 := []byte("foo!")
+	_, err = l.Write(b2)
+	isNil(err, t)
+	existsWithContent(filename, b2, t)
+	fileCount(dir, 1, t)
+}
+
+func TestReopenConcurrentWrites(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReopenConcurrentWrites", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	done := make(chan error)
+	go func() {
+		var err error
+		for i := 0; i < 100 && err == nil; i++ {
+			_, err = l.Write(b)
+		}
+		done <- err
+	}()
+	for i := 0; i < 10; i++ {
+		isNil(l.Reopen(), t)
+	}
+	isNil(<-done, t)
+
+	existsWithContent(filename, bytes.Repeat(b, 100), t)
+}
+
 func TestCompressBackupsWithTimeOnRotate(t *testing.T) {
 	currentTime = fakeTime
 
@@ -1138,3 +1229,143 @@ func exists(path string, t testing.TB) {
 	_, err := os.Stat(path)
 	assertUp(err == nil, t, 1, "expected file to exist, but got error from os.Stat: %v", err)
 }
+
+func TestRotateOnOpen(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	isNil(fsys.MkdirAll("/logs", 0755), t)
+	isNil(writeFile(fsys, logFile("/logs"), []byte("previous run\n"), 0644), t)
+
+	l := &Logger{
+		Filename:     logFile("/logs"),
+		RotateOnOpen: true,
+		FS:           fsys,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	memExistsWithContent(fsys, backupFileWithOrder("/logs", 1), []byte("previous run\n"), t)
+	memExistsWithContent(fsys, logFile("/logs"), b, t)
+
+	// only the first open rotates.
+	isNil(l.Close(), t)
+	_, err = l.Write(b)
+	isNil(err, t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("boo!boo!"), t)
+	memFileCount(fsys, "/logs", 2, t)
+}
+
+func TestRotateOnOpenRestarts(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+
+	// every run is a new Logger, as in a new process.
+	for i := 1; i <= 3; i++ {
+		l := &Logger{
+			Filename:     logFile("/logs"),
+			RotateOnOpen: true,
+			FS:           fsys,
+		}
+		_, err := l.Write([]byte(fmt.Sprintf("run%d\n", i)))
+		isNil(err, t)
+		isNil(l.Close(), t)
+	}
+
+	// the backups of earlier runs were not overwritten.
+	memExistsWithContent(fsys, backupFileWithOrder("/logs", 1), []byte("run1\n"), t)
+	memExistsWithContent(fsys, backupFileWithOrder("/logs", 2), []byte("run2\n"), t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("run3\n"), t)
+	memFileCount(fsys, "/logs", 3, t)
+}
+
+func TestRotateOnOpenAfterReopen(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	isNil(fsys.MkdirAll("/logs", 0755), t)
+	isNil(writeFile(fsys, logFile("/logs"), []byte("previous run\n"), 0644), t)
+
+	l := &Logger{
+		Filename:     logFile("/logs"),
+		RotateOnOpen: true,
+		FS:           fsys,
+	}
+	defer l.Close()
+
+	// Reopen opens the file first, so later opens don't rotate.
+	isNil(l.Reopen(), t)
+	isNil(l.Close(), t)
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("previous run\nboo!"), t)
+	memFileCount(fsys, "/logs", 1, t)
+}
+
+func TestMaxFileAge(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	isNil(fsys.MkdirAll("/logs", 0755), t)
+	isNil(writeFile(fsys, logFile("/logs"), []byte("fresh\n"), 0644), t)
+
+	l := &Logger{
+		Filename:   logFile("/logs"),
+		MaxFileAge: 24 * time.Hour,
+		FS:         fsys,
+	}
+	defer l.Close()
+
+	// the file is young enough to append to.
+	_, err := l.Write([]byte("one\n"))
+	isNil(err, t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("fresh\none\n"), t)
+
+	// two days later the next write rotates it first.
+	newFakeTime()
+	_, err = l.Write([]byte("two\n"))
+	isNil(err, t)
+	memExistsWithContent(fsys, backupFileWithOrder("/logs", 1), []byte("fresh\none\n"), t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("two\n"), t)
+
+	// a file that went stale while closed is rotated on open.
+	isNil(l.Close(), t)
+	newFakeTime()
+	_, err = l.Write([]byte("three\n"))
+	isNil(err, t)
+	memExistsWithContent(fsys, backupFileWithOrder("/logs", 2), []byte("two\n"), t)
+	memExistsWithContent(fsys, logFile("/logs"), []byte("three\n"), t)
+}
+
+func TestCleanupInterval(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	l := &Logger{
+		Filename:        logFile("/logs"),
+		MaxBackups:      1,
+		CleanupInterval: time.Millisecond,
+		FS:              fsys,
+	}
+	defer l.Close()
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	// backups appearing without a rotation are cleaned up in the
+	// background.
+	for i := 1; i <= 3; i++ {
+		newFakeTime()
+		isNil(writeFile(fsys, backupFileWithOrder("/logs", i), []byte("old"), 0644), t)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		files, err := fsys.ReadDir("/logs")
+		isNil(err, t)
+		if len(files) == 2 {
+			break
+		}
+		assert(time.Now().Before(deadline), t, "expected old backups to be removed, got %d files", len(files))
+		time.Sleep(time.Millisecond)
+	}
+	memExistsWithContent(fsys, backupFileWithOrder("/logs", 3), []byte("old"), t)
+
+	isNil(l.Close(), t)
+	assert(l.janitor == nil, t, "expected the cleanup to stop on Close")
+}
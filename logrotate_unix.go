@@ -10,12 +10,21 @@ import (
 // osChown is a var so we can mock it out during tests.
 var osChown = os.Chown
 
-func chown(name string, info os.FileInfo) error {
-	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+func chown(fsys FS, name string, info os.FileInfo) error {
+	f, err := fsys.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
 	if err != nil {
 		return err
 	}
 	f.Close()
-	stat := info.Sys().(*syscall.Stat_t)
-	return osChown(name, int(stat.Uid), int(stat.Gid))
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		// the file system has no owners.
+		return nil
+	}
+	return fsys.Chown(name, int(stat.Uid), int(stat.Gid))
+}
+
+// chownIDs changes the owner and group of name. An ID of -1 is left unchanged.
+func chownIDs(name string, uid, gid int) error {
+	return osChown(name, uid, gid)
 }
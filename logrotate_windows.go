@@ -4,6 +4,10 @@ import (
 	"os"
 )
 
-func chown(_ string, _ os.FileInfo) error {
+func chown(_ FS, _ string, _ os.FileInfo) error {
+	return nil
+}
+
+func chownIDs(_ string, _, _ int) error {
 	return nil
 }
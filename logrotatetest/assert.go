@@ -0,0 +1,90 @@
+package logrotatetest
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"testing"
+
+	logrotate "github.com/fahedouch/go-logrotate"
+)
+
+// Backups returns the backups of l, newest first, failing t if they can't be
+// listed.
+func Backups(t testing.TB, l *logrotate.Logger) []logrotate.BackupInfo {
+	t.Helper()
+	backups, err := l.Backups()
+	if err != nil {
+		t.Fatalf("can't list backups: %s", err)
+	}
+	return backups
+}
+
+// BackupNames returns the names of the backups of l, newest first.
+func BackupNames(t testing.TB, l *logrotate.Logger) []string {
+	t.Helper()
+	var names []string
+	for _, b := range Backups(t, l) {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// AssertBackupCount checks that l has n backups.
+func AssertBackupCount(t testing.TB, l *logrotate.Logger, n int) {
+	t.Helper()
+	if names := BackupNames(t, l); len(names) != n {
+		t.Errorf("expected %d backups, got %d: %q", n, len(names), names)
+	}
+}
+
+// AssertBackups checks that the backups of l are exactly the given names, in
+// any order.
+func AssertBackups(t testing.TB, l *logrotate.Logger, names ...string) {
+	t.Helper()
+	got := BackupNames(t, l)
+	want := append([]string(nil), names...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Errorf("expected backups %q, got %q", want, got)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("expected backups %q, got %q", want, got)
+			return
+		}
+	}
+}
+
+// AssertCompressed checks that every backup of l is compressed if compressed
+// is set, and that none is otherwise.
+func AssertCompressed(t testing.TB, l *logrotate.Logger, compressed bool) {
+	t.Helper()
+	for _, b := range Backups(t, l) {
+		if b.Compressed != compressed {
+			t.Errorf("expected backup %s to have compressed %t", b.Name, compressed)
+		}
+	}
+}
+
+// AssertBackupContent checks that the original content of the backup of l
+// named name, decrypted and decompressed, is content.
+func AssertBackupContent(t testing.TB, l *logrotate.Logger, name string, content []byte) {
+	t.Helper()
+	r, err := l.OpenBackup(name)
+	if err != nil {
+		t.Errorf("can't open backup: %s", err)
+		return
+	}
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Errorf("can't read backup %s: %s", name, err)
+		return
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("expected backup %s to contain %q, got %q", name, content, got)
+	}
+}
@@ -0,0 +1,106 @@
+// Package logrotatetest provides helpers to test code using logrotate.
+package logrotatetest
+
+import (
+	"sync"
+	"time"
+
+	logrotate "github.com/fahedouch/go-logrotate"
+)
+
+// FakeClock is a logrotate.Clock whose time only changes when Set or Advance
+// is called. Its tickers fire as the time passes their period. It is safe
+// for concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// ensure we always implement logrotate.Clock
+var _ logrotate.Clock = (*FakeClock)(nil)
+
+// NewFakeClock returns a FakeClock set to t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d, firing the tickers that are due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set sets the time of the clock to t, firing the tickers that are due.
+// Setting it back in time does not fire any ticker.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(t)
+}
+
+// set sets the time to t. The caller must hold c.mu.
+func (c *FakeClock) set(t time.Time) {
+	c.now = t
+	active := c.tickers[:0]
+	for _, tk := range c.tickers {
+		if tk.stopped {
+			continue
+		}
+		// Like time.Ticker, ticks are dropped when the receiver is
+		// not keeping up.
+		for !tk.next.After(t) {
+			select {
+			case tk.c <- tk.next:
+			default:
+			}
+			tk.next = tk.next.Add(tk.period)
+		}
+		active = append(active, tk)
+	}
+	c.tickers = active
+}
+
+// NewTicker returns a Ticker firing every d of the time of the clock.
+func (c *FakeClock) NewTicker(d time.Duration) logrotate.Ticker {
+	if d <= 0 {
+		panic("logrotatetest: non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tk := &fakeTicker{
+		clock:  c,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, tk)
+	return tk
+}
+
+// fakeTicker is a Ticker of a FakeClock.
+type fakeTicker struct {
+	clock   *FakeClock
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func (tk *fakeTicker) C() <-chan time.Time {
+	return tk.c
+}
+
+func (tk *fakeTicker) Stop() {
+	tk.clock.mu.Lock()
+	defer tk.clock.mu.Unlock()
+	tk.stopped = true
+}
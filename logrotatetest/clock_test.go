@@ -0,0 +1,140 @@
+package logrotatetest
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	logrotate "github.com/fahedouch/go-logrotate"
+)
+
+func TestFakeClockTicker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	tk := c.NewTicker(time.Minute)
+	defer tk.Stop()
+
+	c.Advance(30 * time.Second)
+	select {
+	case <-tk.C():
+		t.Fatal("ticker fired too early")
+	default:
+	}
+
+	c.Advance(30 * time.Second)
+	select {
+	case tick := <-tk.C():
+		if !tick.Equal(start.Add(time.Minute)) {
+			t.Fatalf("expected tick at %v, got %v", start.Add(time.Minute), tick)
+		}
+	default:
+		t.Fatal("ticker did not fire")
+	}
+
+	tk.Stop()
+	c.Advance(time.Hour)
+	select {
+	case <-tk.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
+
+// TestLoggerClock runs Loggers with their own clocks in parallel.
+func TestLoggerClock(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		i := i
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			start := time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC)
+			c := NewFakeClock(start)
+			fs := logrotate.NewMemFS()
+			fs.Clock = c
+			dir := "/logs"
+			l := &logrotate.Logger{
+				Filename:           filepath.Join(dir, "foo.log"),
+				FilenameTimeFormat: "2006-01-02",
+				MaxAge:             1,
+				FS:                 fs,
+				Clock:              c,
+			}
+			defer l.Close()
+
+			if _, err := l.Write([]byte("boo!")); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Rotate(); err != nil {
+				t.Fatal(err)
+			}
+			first := filepath.Join(dir, "foo-"+start.Format("2006-01-02")+".log")
+			if _, err := fs.Stat(first); err != nil {
+				t.Fatalf("expected backup %s: %v", first, err)
+			}
+
+			// two days later the first backup has expired.
+			c.Advance(48 * time.Hour)
+			if err := l.Rotate(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fs.Stat(first); err == nil {
+				t.Fatalf("expected backup %s to be removed", first)
+			}
+			second := filepath.Join(dir, "foo-"+c.Now().Format("2006-01-02")+".log")
+			if _, err := fs.Stat(second); err != nil {
+				t.Fatalf("expected backup %s: %v", second, err)
+			}
+		})
+	}
+}
+
+func TestFakeClockRetentionOnDisk(t *testing.T) {
+	t.Parallel()
+	c := NewFakeClock(time.Now())
+	l := NewLogger(t, func(l *logrotate.Logger) {
+		l.MaxAge = 1
+		l.Clock = c
+	})
+
+	if _, err := l.Write([]byte("old\n")); err != nil {
+		t.Fatal(err)
+	}
+	Rotate(t, l)
+
+	// the backup rotated three days later on the clock is kept, although
+	// its file was born at about the same real time as the first one.
+	c.Advance(72 * time.Hour)
+	if _, err := l.Write([]byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	Rotate(t, l)
+	AssertBackups(t, l, "test.log.2")
+	AssertBackupContent(t, l, "test.log.2", []byte("new\n"))
+}
+
+func TestFakeClockMaxFileAge(t *testing.T) {
+	t.Parallel()
+	c := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewLogger(t, func(l *logrotate.Logger) {
+		l.MaxFileAge = time.Hour
+		l.Clock = c
+	})
+
+	if _, err := l.Write([]byte("one\n")); err != nil {
+		t.Fatal(err)
+	}
+	// the age of the reopened file is measured on the clock, and not from
+	// its birth time on the system clock.
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Write([]byte("two\n")); err != nil {
+		t.Fatal(err)
+	}
+	c.Advance(3 * time.Hour)
+	if _, err := l.Write([]byte("three\n")); err != nil {
+		t.Fatal(err)
+	}
+	AssertBackupCount(t, l, 1)
+	AssertBackupContent(t, l, "test.log.1", []byte("one\ntwo\n"))
+}
@@ -0,0 +1,33 @@
+package logrotatetest
+
+import (
+	"testing"
+	"time"
+
+	logrotate "github.com/fahedouch/go-logrotate"
+)
+
+func TestCleanupIntervalWithFakeClock(t *testing.T) {
+	t.Parallel()
+	c := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	fs := logrotate.NewMemFS()
+	fs.Clock = c
+	l := NewLogger(t, func(l *logrotate.Logger) {
+		l.Filename = "/logs/test.log"
+		l.MaxAge = 1
+		l.CleanupInterval = time.Hour
+		l.FS = fs
+		l.Clock = c
+	})
+
+	if _, err := l.Write([]byte("boo!")); err != nil {
+		t.Fatal(err)
+	}
+	Rotate(t, l)
+	AssertBackupCount(t, l, 1)
+
+	// nothing is written any more, but the backup still expires.
+	c.Advance(48 * time.Hour)
+	Cleanup(t, l)
+	AssertBackupCount(t, l, 0)
+}
@@ -0,0 +1,46 @@
+package logrotatetest
+
+import (
+	"path/filepath"
+	"testing"
+
+	logrotate "github.com/fahedouch/go-logrotate"
+)
+
+// NewLogger returns a Logger writing to `test.log` in a temporary directory
+// of t, which is removed, and the Logger closed, when the test ends.
+// configure, if not nil, is called with the Logger before it is returned to
+// set its other fields, or to change its Filename.
+func NewLogger(t testing.TB, configure func(l *logrotate.Logger)) *logrotate.Logger {
+	t.Helper()
+	l := &logrotate.Logger{
+		Filename: filepath.Join(t.TempDir(), "test.log"),
+	}
+	if configure != nil {
+		configure(l)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// Rotate forces the rotation of l and waits until the compression and
+// removal of old log files that follow it are done, including any running in
+// the background, failing t if anything goes wrong.
+func Rotate(t testing.TB, l *logrotate.Logger) {
+	t.Helper()
+	if err := l.Rotate(); err != nil {
+		t.Fatalf("can't rotate log file: %s", err)
+	}
+	Cleanup(t, l)
+}
+
+// Cleanup waits for the background cleanup of l to finish and then cleans
+// up old log files at the current time of its Clock, failing t if anything
+// goes wrong. After advancing a FakeClock, it leaves the old log files as the
+// CleanupInterval of l would, without waiting for its next tick.
+func Cleanup(t testing.TB, l *logrotate.Logger) {
+	t.Helper()
+	if err := l.Cleanup(); err != nil {
+		t.Fatalf("can't clean up old log files: %s", err)
+	}
+}
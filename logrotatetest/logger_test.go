@@ -0,0 +1,32 @@
+package logrotatetest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	logrotate "github.com/fahedouch/go-logrotate"
+)
+
+func TestHelpers(t *testing.T) {
+	t.Parallel()
+	c := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewLogger(t, func(l *logrotate.Logger) {
+		l.Compress = true
+		l.MaxBackups = 2
+		l.Clock = c
+	})
+
+	for i := 1; i <= 3; i++ {
+		if _, err := fmt.Fprintf(l, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+		c.Advance(time.Hour)
+		Rotate(t, l)
+	}
+
+	AssertBackupCount(t, l, 2)
+	AssertBackups(t, l, "test.log.2.gz", "test.log.3.gz")
+	AssertCompressed(t, l, true)
+	AssertBackupContent(t, l, "test.log.3.gz", []byte("line 3\n"))
+}
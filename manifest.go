@@ -0,0 +1,255 @@
+package logrotate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// manifestSuffix is appended to the log file name to name the manifest.
+const manifestSuffix = ".manifest.json"
+
+// manifestEntry records a backup in the manifest.
+type manifestEntry struct {
+	// Name is the current base name of the backup, including the compression
+	// and encryption suffixes once it is compressed or encrypted.
+	Name string `json:"name"`
+	// Order is the order in the name of backups with the standard file name
+	// format.
+	Order int `json:"order,omitempty"`
+	// RotatedAt is the time the backup was rotated at.
+	RotatedAt time.Time `json:"rotatedAt"`
+	// Size is the size of the backup before compression.
+	Size int64 `json:"size"`
+	// CompressedSize is the size of the backup once compressed or encrypted.
+	CompressedSize int64 `json:"compressedSize,omitempty"`
+	// SHA256 is the hex encoded SHA-256 checksum of the uncompressed backup.
+	SHA256 string `json:"sha256"`
+	// PrevSHA256 is the checksum of the previous backup when backups are
+	// chained, see Logger.Checksum.
+	PrevSHA256 string `json:"prevSha256,omitempty"`
+	// Signature is the hex encoded ed25519 signature of the entry when a
+	// Logger.SigningKey is set.
+	Signature string `json:"signature,omitempty"`
+	// Removed reports whether the entry records the removal of the backup
+	// by the cleanup of old log files rather than its rotation. When backups
+	// are chained, such an entry is appended and the entry of the rotation
+	// is kept; otherwise the entry of the rotation is dropped.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// manifestHead records the last entry of a chained manifest, so that entries
+// cut off its end are detected.
+type manifestHead struct {
+	// Count is the number of entries.
+	Count int `json:"count"`
+	// SHA256 is the hex encoded SHA-256 checksum of the signed data of the
+	// last entry.
+	SHA256 string `json:"sha256,omitempty"`
+	// Signature is the hex encoded ed25519 signature of the head when a
+	// Logger.SigningKey is set.
+	Signature string `json:"signature,omitempty"`
+}
+
+// manifest is the content of the manifest file.
+type manifest struct {
+	Backups []manifestEntry `json:"backups"`
+	Head    *manifestHead   `json:"head,omitempty"`
+}
+
+// manifestEnabled reports whether the Logger keeps a manifest.
+func (l *Logger) manifestEnabled() bool {
+	return l.Manifest || l.Checksum
+}
+
+// manifestPath returns the path of the manifest of the Logger.
+func (l *Logger) manifestPath() string {
+	return l.filename() + manifestSuffix
+}
+
+// loadManifest reads the manifest of the Logger. A missing manifest is empty.
+func (l *Logger) loadManifest() (*manifest, error) {
+	m := &manifest{}
+	data, err := readFile(l.fs(), l.manifestPath())
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read manifest: %s", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("can't decode manifest: %s", err)
+	}
+	return m, nil
+}
+
+// saveManifest atomically replaces the manifest of the Logger with m.
+func (l *Logger) saveManifest(m *manifest) error {
+	if l.Checksum {
+		m.Head = m.head()
+		if l.SigningKey != nil {
+			m.Head.Signature = hex.EncodeToString(ed25519.Sign(l.SigningKey, m.Head.signedData()))
+		}
+	}
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return fmt.Errorf("can't encode manifest: %s", err)
+	}
+	tmp := l.manifestPath() + ".tmp"
+	if err := writeFile(l.fs(), tmp, data, 0600); err != nil {
+		return fmt.Errorf("can't write manifest: %s", err)
+	}
+	if err := l.fs().Rename(tmp, l.manifestPath()); err != nil {
+		return fmt.Errorf("can't write manifest: %s", err)
+	}
+	return nil
+}
+
+// find returns the entry of the existing backup named name, or nil.
+func (m *manifest) find(name string) *manifestEntry {
+	if i := m.index(name); i >= 0 {
+		return &m.Backups[i]
+	}
+	return nil
+}
+
+// index returns the index of the entry of the existing backup named name, or
+// -1. The entries are searched from the newest, so that a backup recorded as
+// removed is not found.
+func (m *manifest) index(name string) int {
+	for i := len(m.Backups) - 1; i >= 0; i-- {
+		if m.Backups[i].Name == name {
+			if m.Backups[i].Removed {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// forget records in m that the backup named name no longer exists. When
+// backups are chained, an entry recording the removal is appended; otherwise
+// the entry of the backup is dropped.
+func (l *Logger) forget(m *manifest, name string) {
+	i := m.index(name)
+	if i < 0 {
+		return
+	}
+	if !l.Checksum {
+		m.Backups = append(m.Backups[:i], m.Backups[i+1:]...)
+		return
+	}
+	e := m.Backups[i]
+	e.Removed = true
+	l.appendEntry(m, e)
+}
+
+// appendEntry appends e to m, chaining and signing it when backups are
+// chained.
+func (l *Logger) appendEntry(m *manifest, e manifestEntry) {
+	e.PrevSHA256 = ""
+	e.Signature = ""
+	if l.Checksum {
+		if n := len(m.Backups); n > 0 {
+			e.PrevSHA256 = m.Backups[n-1].SHA256
+		}
+		if l.SigningKey != nil {
+			e.Signature = hex.EncodeToString(ed25519.Sign(l.SigningKey, e.signedData()))
+		}
+	}
+	m.Backups = append(m.Backups, e)
+}
+
+// head returns the unsigned head of the entries of m.
+func (m *manifest) head() *manifestHead {
+	h := &manifestHead{Count: len(m.Backups)}
+	if n := len(m.Backups); n > 0 {
+		sum := sha256.Sum256(m.Backups[n-1].signedData())
+		h.SHA256 = hex.EncodeToString(sum[:])
+	}
+	return h
+}
+
+// recordBackup adds the backup at path, rotated at rotatedAt, to the manifest.
+func (l *Logger) recordBackup(path string, rotatedAt time.Time) error {
+	m, err := l.loadManifest()
+	if err != nil {
+		return err
+	}
+	sum, size, err := fileChecksum(l.fs(), path)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(path)
+	// A backup with the same name has just been overwritten, which happens
+	// when FilenameTimeFormat is coarser than the time between rotations.
+	for _, suffix := range backupSuffixes {
+		l.forget(m, name+suffix)
+	}
+	e := manifestEntry{
+		Name:      name,
+		RotatedAt: rotatedAt,
+		Size:      size,
+		SHA256:    sum,
+	}
+	if l.FilenameTimeFormat == "" {
+		e.Order = l.backupOrder(name)
+	}
+	l.appendEntry(m, e)
+	return l.saveManifest(m)
+}
+
+// updateManifest records in the manifest the renaming by compression or
+// encryption and the removal of old log files done by millRun, and forgets
+// the backups that no longer exist.
+func (l *Logger) updateManifest(renamed map[string]string, removed []string) error {
+	m, err := l.loadManifest()
+	if err != nil {
+		return err
+	}
+	for _, name := range removed {
+		l.forget(m, name)
+	}
+	for name, newName := range renamed {
+		if e := m.find(name); e != nil {
+			e.Name = newName
+			if info, err := l.fs().Stat(filepath.Join(l.dir(), e.Name)); err == nil {
+				e.CompressedSize = info.Size()
+			}
+		}
+	}
+	if !l.Checksum {
+		// Chained backups that vanished are reported by Verify instead.
+		var kept []manifestEntry
+		for _, e := range m.Backups {
+			if _, err := l.fs().Stat(filepath.Join(l.dir(), e.Name)); err == nil {
+				kept = append(kept, e)
+			}
+		}
+		m.Backups = kept
+	}
+	return l.saveManifest(m)
+}
+
+// fileChecksum returns the hex encoded SHA-256 checksum and the size of the
+// file at path.
+func fileChecksum(fsys FS, path string) (string, int64, error) {
+	f, err := open(fsys, path)
+	if err != nil {
+		return "", 0, fmt.Errorf("can't open backup file: %s", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("can't read backup file: %s", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
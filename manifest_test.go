@@ -0,0 +1,88 @@
+package logrotate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifestRecordsBackups(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestManifestRecordsBackups", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+		Manifest: true,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+
+	m, err := l.loadManifest()
+	isNil(err, t)
+	equals(1, len(m.Backups), t)
+	e := m.Backups[0]
+	equals(filepath.Base(backupFileWithOrder(dir, 1))+compressSuffix, e.Name, t)
+	equals(1, e.Order, t)
+	assert(e.RotatedAt.Equal(fakeTime()), t, "expected rotation time %v, got %v", fakeTime(), e.RotatedAt)
+	equals(int64(len(b)), e.Size, t)
+	info, err := os.Stat(filepath.Join(dir, e.Name))
+	isNil(err, t)
+	equals(info.Size(), e.CompressedSize, t)
+	sum := sha256.Sum256(b)
+	equals(hex.EncodeToString(sum[:]), e.SHA256, t)
+
+	// The active file, the compressed backup and the manifest.
+	fileCount(dir, 3, t)
+}
+
+func TestManifestRetention(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestManifestRetention", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+		Manifest: true,
+		MaxAge:   1,
+	}
+	defer l.Close()
+	for i := 0; i < 2; i++ {
+		_, err := l.Write([]byte("boo!"))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+	}
+	exists(backupFileWithOrder(dir, 1)+compressSuffix, t)
+	exists(backupFileWithOrder(dir, 2)+compressSuffix, t)
+
+	// The birth time of the compressed files is recent, but the manifest
+	// says the first backup was rotated long ago.
+	m, err := l.loadManifest()
+	isNil(err, t)
+	m.find(filepath.Base(backupFileWithOrder(dir, 1)) + compressSuffix).RotatedAt = fakeTime().Add(-72 * time.Hour)
+	isNil(l.saveManifest(m), t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals(2, backups[0].Order, t)
+	equals(1, backups[1].Order, t)
+
+	isNil(l.Rotate(), t)
+	notExist(backupFileWithOrder(dir, 1)+compressSuffix, t)
+	exists(backupFileWithOrder(dir, 2)+compressSuffix, t)
+
+	m, err = l.loadManifest()
+	isNil(err, t)
+	equals(2, len(m.Backups), t)
+	notNil(m.find(filepath.Base(backupFileWithOrder(dir, 2))+compressSuffix), t)
+	notNil(m.find(filepath.Base(backupFileWithOrder(dir, 3))+compressSuffix), t)
+}
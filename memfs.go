@@ -0,0 +1,476 @@
+package logrotate
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an FS keeping files in memory, to test rotation, retention and
+// compression without touching the disk. Like on unix, files that are
+// removed or renamed while open remain readable and writable.
+//
+// Fail, if set, is called before every operation with its name, such as
+// "rename" or "write", and the path it applies to. A non-nil result makes the
+// operation fail with that error, which allows to simulate a full disk or a
+// failing rename.
+//
+// Clock, if set, is the source of the modification and birth times of files.
+// It defaults to the system clock.
+//
+// The zero value is an empty MemFS ready to use.
+type MemFS struct {
+	Fail  func(op, name string) error
+	Clock Clock
+
+	mu    sync.Mutex
+	nodes map[string]*memNode
+}
+
+// ensure we always implement FS and SameFiler
+var (
+	_ FS        = (*MemFS)(nil)
+	_ SameFiler = (*MemFS)(nil)
+)
+
+// memNode is a file or directory of a MemFS.
+type memNode struct {
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+	birth   time.Time
+	uid     int
+	gid     int
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: make(map[string]*memNode)}
+}
+
+// Owner returns the user and group IDs of name, as set by Chown.
+func (m *MemFS) Owner(name string) (uid, gid int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("stat", name)
+	if err != nil {
+		return 0, 0, err
+	}
+	return n.uid, n.gid, nil
+}
+
+// now returns the current time of the Clock of the MemFS.
+func (m *MemFS) now() time.Time {
+	if m.Clock != nil {
+		return m.Clock.Now()
+	}
+	return systemClock{}.Now()
+}
+
+// put adds n at the clean path name. It must be called with m.mu held.
+func (m *MemFS) put(name string, n *memNode) {
+	if m.nodes == nil {
+		m.nodes = make(map[string]*memNode)
+	}
+	m.nodes[name] = n
+}
+
+// fail returns the injected failure of op on name, if any.
+func (m *MemFS) fail(op, name string) error {
+	if m.Fail == nil {
+		return nil
+	}
+	if err := m.Fail(op, name); err != nil {
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return nil
+}
+
+// node returns the node of name. The caller must hold m.mu.
+func (m *MemFS) node(op, name string) (*memNode, error) {
+	n, ok := m.nodes[filepath.Clean(name)]
+	if !ok {
+		return nil, &os.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return n, nil
+}
+
+// parentExists reports whether the directory of name exists. The caller must
+// hold m.mu.
+func (m *MemFS) parentExists(name string) bool {
+	dir := filepath.Dir(filepath.Clean(name))
+	if dir == filepath.Clean(name) {
+		return true
+	}
+	n, ok := m.nodes[dir]
+	return ok && n.mode.IsDir()
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if err := m.fail("open", name); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	n, ok := m.nodes[name]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		if !m.parentExists(name) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		now := m.now()
+		n = &memNode{mode: perm.Perm(), modTime: now, birth: now}
+		m.put(name, n)
+	case n.mode.IsDir() && flag&(os.O_WRONLY|os.O_RDWR) != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	if flag&os.O_TRUNC != 0 && !n.mode.IsDir() {
+		n.data = nil
+		n.modTime = m.now()
+	}
+	return &memFile{
+		fs:     m,
+		node:   n,
+		name:   name,
+		flag:   flag,
+		append: flag&os.O_APPEND != 0,
+	}, nil
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	if err := m.fail("stat", name); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(filepath.Base(name)), nil
+}
+
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	if err := m.fail("rename", oldpath); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("rename", oldpath)
+	if err != nil {
+		return err
+	}
+	if n.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errors.New("renaming directories is not supported")}
+	}
+	if !m.parentExists(newpath) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	delete(m.nodes, filepath.Clean(oldpath))
+	m.put(filepath.Clean(newpath), n)
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	if err := m.fail("remove", name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("remove", name)
+	if err != nil {
+		return err
+	}
+	name = filepath.Clean(name)
+	if n.mode.IsDir() {
+		for p := range m.nodes {
+			if filepath.Dir(p) == name && p != name {
+				return &os.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+			}
+		}
+	}
+	delete(m.nodes, name)
+	return nil
+}
+
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	if err := m.fail("mkdir", path); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	for {
+		if n, ok := m.nodes[path]; ok {
+			if !n.mode.IsDir() {
+				return &os.PathError{Op: "mkdir", Path: path, Err: errors.New("not a directory")}
+			}
+		} else {
+			now := m.now()
+			m.put(path, &memNode{mode: os.ModeDir | perm.Perm(), modTime: now, birth: now})
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return nil
+		}
+		path = parent
+	}
+}
+
+func (m *MemFS) ReadDir(name string) ([]os.DirEntry, error) {
+	if err := m.fail("readdir", name); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.mode.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	name = filepath.Clean(name)
+	var entries []os.DirEntry
+	for p, child := range m.nodes {
+		if p != name && filepath.Dir(p) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(child.info(filepath.Base(p))))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (m *MemFS) Chtimes(name string, atime, mtime time.Time) error {
+	if err := m.fail("chtimes", name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("chtimes", name)
+	if err != nil {
+		return err
+	}
+	n.modTime = mtime
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode os.FileMode) error {
+	if err := m.fail("chmod", name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("chmod", name)
+	if err != nil {
+		return err
+	}
+	n.mode = n.mode&os.ModeType | mode.Perm()
+	return nil
+}
+
+func (m *MemFS) Chown(name string, uid, gid int) error {
+	if err := m.fail("chown", name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("chown", name)
+	if err != nil {
+		return err
+	}
+	if uid != -1 {
+		n.uid = uid
+	}
+	if gid != -1 {
+		n.gid = gid
+	}
+	return nil
+}
+
+func (m *MemFS) BirthTime(name string) (time.Time, bool, error) {
+	if err := m.fail("stat", name); err != nil {
+		return time.Time{}, false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("stat", name)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return n.birth, true, nil
+}
+
+func (m *MemFS) SameFile(a, b os.FileInfo) bool {
+	ma, ok := a.(*memFileInfo)
+	if !ok {
+		return false
+	}
+	mb, ok := b.(*memFileInfo)
+	return ok && ma.node == mb.node
+}
+
+// info returns the FileInfo of n, named name.
+func (n *memNode) info(name string) os.FileInfo {
+	return &memFileInfo{name: name, size: int64(len(n.data)), mode: n.mode, modTime: n.modTime, node: n}
+}
+
+// memFileInfo is the os.FileInfo of a MemFS file.
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	// node identifies the file, for SameFile.
+	node *memNode
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *memFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *memFileInfo) Sys() interface{}   { return nil }
+
+// memFile is an open file of a MemFS.
+type memFile struct {
+	fs     *MemFS
+	node   *memNode
+	name   string
+	flag   int
+	append bool
+	offset int64
+	closed bool
+}
+
+func (f *memFile) check(op string, write bool) error {
+	if f.closed {
+		return &os.PathError{Op: op, Path: f.name, Err: os.ErrClosed}
+	}
+	if write && f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return &os.PathError{Op: op, Path: f.name, Err: errors.New("file not open for writing")}
+	}
+	if !write && f.flag&os.O_WRONLY != 0 {
+		return &os.PathError{Op: op, Path: f.name, Err: errors.New("file not open for reading")}
+	}
+	return nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if err := f.fs.fail("read", f.name); err != nil {
+		return 0, err
+	}
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check("read", false); err != nil {
+		return 0, err
+	}
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if err := f.fs.fail("write", f.name); err != nil {
+		return 0, err
+	}
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check("write", true); err != nil {
+		return 0, err
+	}
+	if f.append {
+		f.offset = int64(len(f.node.data))
+	}
+	if end := f.offset + int64(len(p)); end > int64(len(f.node.data)) {
+		f.node.data = append(f.node.data, make([]byte, end-int64(len(f.node.data)))...)
+	}
+	copy(f.node.data[f.offset:], p)
+	f.offset += int64(len(p))
+	f.node.modTime = f.fs.now()
+	return len(p), nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrClosed}
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: errors.New("negative offset")}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return &os.PathError{Op: "close", Path: f.name, Err: os.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+func (f *memFile) Name() string {
+	return f.name
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	return f.node.info(filepath.Base(f.name)), nil
+}
+
+func (f *memFile) Sync() error {
+	if err := f.fs.fail("sync", f.name); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (f *memFile) Chmod(mode os.FileMode) error {
+	if err := f.fs.fail("chmod", f.name); err != nil {
+		return err
+	}
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	f.node.mode = f.node.mode&os.ModeType | mode.Perm()
+	return nil
+}
+
+// String returns the paths of the files of the MemFS, one per line, for
+// debugging.
+func (m *MemFS) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	paths := make([]string, 0, len(m.nodes))
+	for p := range m.nodes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, "\n")
+}
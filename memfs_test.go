@@ -0,0 +1,158 @@
+package logrotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// memExistsWithContent checks that the given file of fsys exists and has the
+// correct content.
+func memExistsWithContent(fsys FS, path string, content []byte, t testing.TB) {
+	b, err := readFile(fsys, path)
+	isNilUp(err, t, 1)
+	equalsUp(content, b, t, 1)
+}
+
+// memFileCount checks that the number of files in the directory of fsys is
+// exp.
+func memFileCount(fsys FS, dir string, exp int, t testing.TB) {
+	files, err := fsys.ReadDir(dir)
+	isNilUp(err, t, 1)
+	equalsUp(exp, len(files), t, 1)
+}
+
+func TestMemFSRotateAndRetain(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	dir := "/var/log/app"
+	l := &Logger{
+		Filename:   logFile(dir),
+		MaxBytes:   10,
+		MaxBackups: 1,
+		FS:         fsys,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	memExistsWithContent(fsys, logFile(dir), b, t)
+
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	memExistsWithContent(fsys, backupFileWithOrder(dir, 1), b, t)
+
+	b2 := []byte("foooooo!")
+	_, err = l.Write(b2)
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+
+	// the oldest backup is removed by MaxBackups.
+	memExistsWithContent(fsys, backupFileWithOrder(dir, 2), b2, t)
+	memExistsWithContent(fsys, logFile(dir), []byte{}, t)
+	memFileCount(fsys, dir, 2, t)
+
+	// nothing was written to disk.
+	_, err = os.Stat(dir)
+	assert(os.IsNotExist(err), t, "expected %s not to exist, got %v", dir, err)
+}
+
+func TestMemFSCompress(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	dir := "/logs"
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+		FS:       fsys,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+
+	memExistsWithContent(fsys, backupFileWithOrder(dir, 1)+compressSuffix, gzipped(b, t), t)
+	memFileCount(fsys, dir, 2, t)
+}
+
+func TestMemFSDiskFull(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	dir := "/logs"
+	l := &Logger{
+		Filename: logFile(dir),
+		FS:       fsys,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	fsys.Fail = func(op, name string) error {
+		if op == "write" {
+			return syscall.ENOSPC
+		}
+		return nil
+	}
+	_, err = l.Write([]byte("foo!"))
+	assert(errors.Is(err, syscall.ENOSPC), t, "expected ENOSPC, got %v", err)
+
+	fsys.Fail = nil
+	_, err = l.Write([]byte("bar!"))
+	isNil(err, t)
+	memExistsWithContent(fsys, logFile(dir), []byte("boo!bar!"), t)
+}
+
+func TestMemFSRenameFailure(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	dir := "/logs"
+	l := &Logger{
+		Filename: logFile(dir),
+		FS:       fsys,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	fsys.Fail = func(op, name string) error {
+		if op == "rename" && name == logFile(dir) {
+			return syscall.EACCES
+		}
+		return nil
+	}
+	notNil(l.Rotate(), t)
+
+	// the log file was left in place.
+	fsys.Fail = nil
+	memExistsWithContent(fsys, logFile(dir), b, t)
+	_, err = fsys.Stat(backupFileWithOrder(dir, 1))
+	assert(os.IsNotExist(err), t, "expected no backup, got %v", err)
+	_, err = fsys.Stat(filepath.Join(dir, "foobar.log"+journalSuffix))
+	assert(os.IsNotExist(err), t, "expected no journal, got %v", err)
+}
+
+func TestMemFSZeroValue(t *testing.T) {
+	currentTime = fakeTime
+	fsys := &MemFS{}
+	l := &Logger{
+		Filename: logFile("/logs"),
+		MaxBytes: 10,
+		FS:       fsys,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	memExistsWithContent(fsys, backupFileWithOrder("/logs", 1), []byte("boo!"), t)
+	memFileCount(fsys, "/logs", 2, t)
+}
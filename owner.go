@@ -0,0 +1,70 @@
+package logrotate
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// fileMode returns the mode of new log files, given the mode of the rotated
+// log file.
+func (l *Logger) fileMode(old os.FileMode) os.FileMode {
+	if l.FileMode != 0 {
+		return l.FileMode
+	}
+	return old
+}
+
+// dirMode returns the mode of the directories created for the log file.
+func (l *Logger) dirMode() os.FileMode {
+	if l.DirMode != 0 {
+		return l.DirMode
+	}
+	return 0755
+}
+
+// ownership returns the user and group IDs named by Owner and Group, or -1
+// for the ones that are not set.
+func (l *Logger) ownership() (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if l.Owner != "" {
+		if uid, err = strconv.Atoi(l.Owner); err != nil {
+			u, err := user.Lookup(l.Owner)
+			if err != nil {
+				return -1, -1, fmt.Errorf("can't find log file owner: %s", err)
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return -1, -1, fmt.Errorf("can't find log file owner: %s", err)
+			}
+		}
+	}
+	if l.Group != "" {
+		if gid, err = strconv.Atoi(l.Group); err != nil {
+			g, err := user.LookupGroup(l.Group)
+			if err != nil {
+				return -1, -1, fmt.Errorf("can't find log file group: %s", err)
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return -1, -1, fmt.Errorf("can't find log file group: %s", err)
+			}
+		}
+	}
+	return uid, gid, nil
+}
+
+// applyOwnership gives the file name to Owner and Group, if they are set.
+func (l *Logger) applyOwnership(name string) error {
+	if l.Owner == "" && l.Group == "" {
+		return nil
+	}
+	uid, gid, err := l.ownership()
+	if err != nil {
+		return err
+	}
+	// this is a no-op anywhere but unix
+	if err := l.fs().Chown(name, uid, gid); err != nil {
+		return fmt.Errorf("can't chown log file: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build unix
+
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestFileModes(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestFileModes", t)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "logs", "foobar.log")
+	l := &Logger{
+		Filename:   filename,
+		FileMode:   0640,
+		BackupMode: 0440,
+		DirMode:    0750,
+		Compress:   true,
+	}
+	defer l.Close()
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+
+	modeIs := func(path string, mode os.FileMode) {
+		info, err := os.Stat(path)
+		isNilUp(err, t, 1)
+		equalsUp(mode, info.Mode().Perm(), t, 1)
+	}
+	modeIs(filepath.Dir(filename), 0750)
+	modeIs(filename, 0640)
+	modeIs(filename+".1"+compressSuffix, 0440)
+}
+
+func TestOwnership(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestOwnership", t)
+	defer os.RemoveAll(dir)
+
+	chowned := map[string][2]int{}
+	osChown = func(name string, uid, gid int) error {
+		chowned[filepath.Base(name)] = [2]int{uid, gid}
+		return nil
+	}
+	defer func() { osChown = os.Chown }()
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Owner:    "1234",
+		Group:    strconv.Itoa(os.Getgid()),
+	}
+	defer l.Close()
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+
+	want := [2]int{1234, os.Getgid()}
+	equals(want, chowned["foobar.log"], t)
+	equals(want, chowned["foobar.log.1"], t)
+
+	l.Owner = "no-such-user-for-logrotate"
+	notNil(l.Rotate(), t)
+}
@@ -0,0 +1,140 @@
+package logrotate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// pinsSuffix is appended to the log file name to name the list of pinned
+// backups.
+const pinsSuffix = ".pins.json"
+
+// pinsPath returns the path of the list of pinned backups of the Logger.
+func (l *Logger) pinsPath() string {
+	return l.filename() + pinsSuffix
+}
+
+// Pin exempts the backup named name, as listed by Backups, from retention:
+// it is neither removed nor counted against MaxBackups, whatever its age,
+// until it is unpinned. It is not compressed either, but still encrypted.
+// This keeps the backups covering an incident around until it has been
+// investigated. Pins are kept next to the log file, in
+// `<filename>.pins.json`, and follow the backup when it is compressed or
+// encrypted.
+func (l *Logger) Pin(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	backup, err := l.isBackupName(name)
+	if err != nil {
+		return err
+	}
+	if filepath.Base(name) != name || !backup {
+		return fmt.Errorf("%s is not a backup of %s", name, l.filename())
+	}
+	if _, err := l.fs().Stat(filepath.Join(l.dir(), name)); err != nil {
+		return fmt.Errorf("can't pin backup: %s", err)
+	}
+	pins, err := l.loadPins()
+	if err != nil {
+		return err
+	}
+	pins[baseBackupName(name)] = true
+	return l.savePins(pins)
+}
+
+// Unpin makes the backup named name subject to retention again. Unpinning a
+// backup that is not pinned does nothing.
+func (l *Logger) Unpin(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pins, err := l.loadPins()
+	if err != nil {
+		return err
+	}
+	base := baseBackupName(name)
+	if !pins[base] {
+		return nil
+	}
+	delete(pins, base)
+	return l.savePins(pins)
+}
+
+// Pinned returns the names of the pinned backups that still exist, newest
+// first.
+func (l *Logger) Pinned() ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.dirExists() {
+		return nil, nil
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return nil, err
+	}
+	pins, err := l.loadPins()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if pins[baseBackupName(f.Name())] {
+			names = append(names, f.Name())
+		}
+	}
+	return names, nil
+}
+
+// loadPins returns the base names of the pinned backups. A missing list is
+// empty.
+func (l *Logger) loadPins() (map[string]bool, error) {
+	pins := make(map[string]bool)
+	data, err := readFile(l.fs(), l.pinsPath())
+	if os.IsNotExist(err) {
+		return pins, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read pinned backups: %s", err)
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("can't decode pinned backups: %s", err)
+	}
+	for _, name := range names {
+		pins[name] = true
+	}
+	return pins, nil
+}
+
+// savePins atomically replaces the list of pinned backups with pins. The
+// list is removed when nothing is pinned.
+func (l *Logger) savePins(pins map[string]bool) error {
+	if len(pins) == 0 {
+		if err := l.fs().Remove(l.pinsPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("can't write pinned backups: %s", err)
+		}
+		return nil
+	}
+	names := make([]string, 0, len(pins))
+	for name := range pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	data, err := json.MarshalIndent(names, "", "\t")
+	if err != nil {
+		return fmt.Errorf("can't encode pinned backups: %s", err)
+	}
+	tmp := l.pinsPath() + tmpSuffix
+	if err := writeFile(l.fs(), tmp, data, 0600); err != nil {
+		return fmt.Errorf("can't write pinned backups: %s", err)
+	}
+	if err := l.fs().Rename(tmp, l.pinsPath()); err != nil {
+		return fmt.Errorf("can't write pinned backups: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package logrotate
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPin(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	dir := "/logs"
+	l := &Logger{
+		Filename:   logFile(dir),
+		MaxBackups: 1,
+		Compress:   true,
+		FS:         fsys,
+	}
+	defer l.Close()
+
+	rotate := func(s string) {
+		_, err := l.Write([]byte(s))
+		isNilUp(err, t, 1)
+		newFakeTime()
+		isNilUp(l.Rotate(), t, 1)
+	}
+	first := filepath.Base(backupFileWithOrder(dir, 1)) + compressSuffix
+
+	rotate("one!")
+	isNil(l.Pin(first), t)
+	rotate("two!")
+	rotate("three!")
+
+	// the pinned backup is kept and not counted against MaxBackups.
+	pinned, err := l.Pinned()
+	isNil(err, t)
+	equals([]string{first}, pinned, t)
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	for _, b := range backups {
+		equals(b.Name == first, b.Pinned, t)
+	}
+	memExistsWithContent(fsys, filepath.Join(dir, "foobar.log"+pinsSuffix), []byte("[\n\t\"foobar.log.1\"\n]"), t)
+
+	isNil(l.Unpin(first), t)
+	rotate("four!")
+	pinned, err = l.Pinned()
+	isNil(err, t)
+	equals(0, len(pinned), t)
+	_, err = fsys.Stat(filepath.Join(dir, first))
+	notNil(err, t)
+	memFileCount(fsys, dir, 2, t)
+
+	notNil(l.Pin(filepath.Base(logFile(dir))), t)
+	notNil(l.Pin(first), t)
+}
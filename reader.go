@@ -0,0 +1,197 @@
+package logrotate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ReaderOptions configures NewReader.
+type ReaderOptions struct {
+	// SkipActive determines if the active log file is left out, so that only
+	// the backups are read.
+	SkipActive bool
+}
+
+// segment is a log file read by NewReader, either a backup or the active file.
+type segment struct {
+	path string
+	// backup reports whether the segment is a backup, which may need to be
+	// decrypted and decompressed.
+	backup bool
+	// end is the time the segment was rotated at. It is zero for the
+	// active file.
+	end time.Time
+}
+
+// NewReader returns a reader over everything the Logger has written that is
+// still on disk: the backups from oldest to newest, followed by the active
+// log file. Backups are decrypted and decompressed transparently; reading
+// encrypted backups requires the Encrypter of the Logger to also be a
+// Decrypter. opts may be nil.
+//
+// The files are listed when NewReader is called, and opened one at a time as
+// reading reaches them, so that a single file descriptor is used however many
+// backups there are. A backup compressed or encrypted in the meantime is read
+// under its new name, and one removed in the meantime is skipped. The active
+// file is read up to its end at the time it is reached.
+func NewReader(l *Logger, opts *ReaderOptions) (io.ReadCloser, error) {
+	if opts == nil {
+		opts = &ReaderOptions{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	segments, err := l.segments(!opts.SkipActive)
+	if err != nil {
+		return nil, err
+	}
+	return l.openSegments(segments), nil
+}
+
+// openSegments returns a segmentReader over the given segments.
+func (l *Logger) openSegments(segments []segment) *segmentReader {
+	return &segmentReader{fsys: l.fs(), segments: segments, decode: l.backupReader}
+}
+
+// openSegment opens s, following a backup that was compressed or encrypted
+// since it was listed.
+func openSegment(fsys FS, s segment) (File, error) {
+	f, err := open(fsys, s.path)
+	if err == nil || !s.backup || !os.IsNotExist(err) {
+		return f, err
+	}
+	base := baseBackupName(s.path)
+	for _, suffix := range backupSuffixes {
+		if f, errSuffix := open(fsys, base+suffix); errSuffix == nil {
+			return f, nil
+		}
+	}
+	return nil, err
+}
+
+// segments returns the backups from oldest to newest, followed by the active
+// log file if active is set and the file exists.
+func (l *Logger) segments(active bool) ([]segment, error) {
+	if !l.dirExists() {
+		return nil, nil
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return nil, err
+	}
+	if l.FilenameTimeFormat == "" {
+		// The order in the name is more reliable than the birth time of
+		// the file, which compression resets.
+		sort.SliceStable(files, func(i, j int) bool {
+			return l.backupOrder(files[i].Name()) > l.backupOrder(files[j].Name())
+		})
+	}
+
+	var segments []segment
+	for i := len(files) - 1; i >= 0; i-- {
+		name := files[i].Name()
+		end := files[i].timestamp
+		if !files[i].rotated {
+			// Backups with the standard file name format get their
+			// modification time set to the rotation time, which
+			// compression and encryption keep.
+			end = files[i].ModTime()
+		}
+		segments = append(segments, segment{
+			path:   filepath.Join(l.dir(), name),
+			backup: true,
+			end:    end,
+		})
+	}
+	if active {
+		if _, err := l.fs().Stat(l.filename()); err == nil {
+			segments = append(segments, segment{path: l.filename()})
+		}
+	}
+	return segments, nil
+}
+
+// backupOrder returns the order in the name of a backup with the standard
+// file name format, or 0 if it has none.
+func (l *Logger) backupOrder(filename string) int {
+	prefix, ext := l.prefixAndExt()
+	for _, suffix := range backupSuffixes {
+		if order, err := l.orderFromName(filename, prefix, ext+suffix); err == nil {
+			return order
+		}
+	}
+	return 0
+}
+
+// dirExists reports whether the directory of the log file exists.
+func (l *Logger) dirExists() bool {
+	info, err := l.fs().Stat(l.dir())
+	return err == nil && info.IsDir()
+}
+
+// segmentReader reads a list of files one after the other, decoding the
+// backups. Each file is opened when reading reaches it and closed at its end.
+type segmentReader struct {
+	fsys     FS
+	segments []segment
+	decode   func(r io.Reader, name string) (io.Reader, error)
+	// f is the file being read, and cur the reader decoding it.
+	f   File
+	cur io.Reader
+	i   int
+}
+
+func (r *segmentReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if r.i >= len(r.segments) {
+				return 0, io.EOF
+			}
+			s := r.segments[r.i]
+			f, err := openSegment(r.fsys, s)
+			if os.IsNotExist(err) {
+				// The file was removed since it was listed, by the
+				// cleanup of old log files.
+				r.i++
+				continue
+			}
+			if err != nil {
+				return 0, fmt.Errorf("can't open log file: %s", err)
+			}
+			r.f, r.cur = f, f
+			if s.backup {
+				dr, err := r.decode(f, filepath.Base(f.Name()))
+				if err != nil {
+					return 0, err
+				}
+				r.cur = dr
+			}
+		}
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.f.Close()
+			r.f, r.cur = nil, nil
+			r.i++
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close closes the file being read, if any.
+func (r *segmentReader) Close() error {
+	r.i = len(r.segments)
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f, r.cur = nil, nil
+	return err
+}
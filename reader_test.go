@@ -0,0 +1,125 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReaderWithOrder(t *testing.T) {
+	currentTime = time.Now
+	dir := makeTempDir("TestReaderWithOrder", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		MaxBytes: 10,
+		Compress: true,
+	}
+	defer l.Close()
+	for _, s := range []string{"one!", "two!", "three!", "four!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+	}
+	_, err := l.Write([]byte("five!"))
+	isNil(err, t)
+	exists(backupFileWithOrder(dir, 1)+compressSuffix, t)
+
+	r, err := NewReader(l, nil)
+	isNil(err, t)
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	isNil(err, t)
+	equals("one!two!three!four!five!", string(b), t)
+}
+
+func TestReaderWithTime(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReaderWithTime", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:           logFile(dir),
+		FilenameTimeFormat: backupTimeFormat,
+		Compress:           true,
+	}
+	defer l.Close()
+	for _, s := range []string{"one!", "two!", "three!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+	}
+	_, err := l.Write([]byte("four!"))
+	isNil(err, t)
+
+	r, err := NewReader(l, nil)
+	isNil(err, t)
+	b, err := io.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals("one!two!three!four!", string(b), t)
+
+	r, err = NewReader(l, &ReaderOptions{SkipActive: true})
+	isNil(err, t)
+	b, err = io.ReadAll(r)
+	isNil(err, t)
+	isNil(r.Close(), t)
+	equals("one!two!three!", string(b), t)
+}
+
+func TestReaderNoFiles(t *testing.T) {
+	l := &Logger{
+		Filename: logFile("TestReaderNoFiles"),
+	}
+	r, err := NewReader(l, nil)
+	isNil(err, t)
+	b, err := io.ReadAll(r)
+	isNil(err, t)
+	equals(0, len(b), t)
+	isNil(r.Close(), t)
+}
+
+func TestReaderOpensLazily(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	var opens atomic.Int32
+	fsys.Fail = func(op, name string) error {
+		if op == "open" {
+			opens.Add(1)
+		}
+		return nil
+	}
+	l := &Logger{
+		Filename: logFile("/logs"),
+		FS:       fsys,
+	}
+	defer l.Close()
+	for _, s := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+	}
+	_, err := l.Write([]byte("three!"))
+	isNil(err, t)
+
+	before := opens.Load()
+	r, err := NewReader(l, nil)
+	isNil(err, t)
+	defer r.Close()
+	equals(before, opens.Load(), t)
+
+	// the first backup is removed and the second one compressed before
+	// they are reached.
+	cfg := l.Config()
+	cfg.Compress = true
+	cfg.MaxBackups = 1
+	isNil(l.Reconfigure(cfg), t)
+	b, err := io.ReadAll(r)
+	isNil(err, t)
+	equals("two!three!", string(b), t)
+}
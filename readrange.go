@@ -0,0 +1,122 @@
+package logrotate
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"time"
+)
+
+// RangeOptions configures ReadRange.
+type RangeOptions struct {
+	// TimeLayout is the time.Time layout of the timestamp leading each log
+	// line. If set, only the lines whose timestamp is within the range are
+	// returned, along with the lines without a timestamp that follow them,
+	// such as the rest of a multi-line message. Otherwise whole log files
+	// are returned.
+	TimeLayout string
+
+	// Location is the location of timestamps without a time zone. It
+	// defaults to UTC.
+	Location *time.Location
+}
+
+// ReadRange returns a reader over what the Logger has written between from
+// and to. Only the log files whose time span overlaps the range are read: a
+// backup spans from the rotation of the previous one to its own rotation
+// time, which comes from its name with a FilenameTimeFormat, from the
+// manifest if there is one and from its modification time otherwise. opts may
+// be nil.
+func (l *Logger) ReadRange(from, to time.Time, opts *RangeOptions) (io.ReadCloser, error) {
+	if opts == nil {
+		opts = &RangeOptions{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	segments, err := l.segments(true)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []segment
+	var start time.Time
+	for _, s := range segments {
+		if (s.end.IsZero() || !s.end.Before(from)) && (start.IsZero() || !start.After(to)) {
+			selected = append(selected, s)
+		}
+		start = s.end
+	}
+	r := l.openSegments(selected)
+	if opts.TimeLayout == "" {
+		return r, nil
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &rangeFilter{
+		rc:     r,
+		br:     bufio.NewReader(r),
+		from:   from,
+		to:     to,
+		layout: opts.TimeLayout,
+		loc:    loc,
+	}, nil
+}
+
+// rangeFilter only lets through the lines of a reader that are within a
+// time range.
+type rangeFilter struct {
+	rc      io.Closer
+	br      *bufio.Reader
+	from    time.Time
+	to      time.Time
+	layout  string
+	loc     *time.Location
+	inRange bool
+	pending []byte
+	err     error
+}
+
+func (f *rangeFilter) Read(p []byte) (int, error) {
+	for len(f.pending) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		line, err := f.br.ReadBytes('\n')
+		f.err = err
+		if len(line) == 0 {
+			continue
+		}
+		if t, ok := f.timestamp(line); ok {
+			f.inRange = !t.Before(f.from) && !t.After(f.to)
+		}
+		if f.inRange {
+			f.pending = line
+		}
+	}
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
+
+// timestamp parses the timestamp leading line. The layout is first assumed
+// to have a fixed width, and then to be followed by a space.
+func (f *rangeFilter) timestamp(line []byte) (time.Time, bool) {
+	if len(line) >= len(f.layout) {
+		if t, err := time.ParseInLocation(f.layout, string(line[:len(f.layout)]), f.loc); err == nil {
+			return t, true
+		}
+	}
+	field := bytes.TrimRight(line, "\r\n")
+	if i := bytes.IndexByte(field, ' '); i >= 0 {
+		field = field[:i]
+	}
+	t, err := time.ParseInLocation(f.layout, string(field), f.loc)
+	return t, err == nil
+}
+
+func (f *rangeFilter) Close() error {
+	return f.rc.Close()
+}
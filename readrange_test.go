@@ -0,0 +1,105 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReadRangeSegments(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReadRangeSegments", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:           logFile(dir),
+		FilenameTimeFormat: backupTimeFormat,
+		Compress:           true,
+	}
+	defer l.Close()
+
+	start := fakeTime().UTC().Truncate(time.Millisecond)
+	for _, s := range []string{"one\n", "two\n", "three\n"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+	}
+	_, err := l.Write([]byte("four\n"))
+	isNil(err, t)
+
+	// Backups span 2 days each: one until start+2d, two until start+4d and
+	// three until start+6d.
+	read := func(from, to time.Time) string {
+		r, err := l.ReadRange(from, to, nil)
+		isNilUp(err, t, 1)
+		defer r.Close()
+		b, err := io.ReadAll(r)
+		isNilUp(err, t, 1)
+		return string(b)
+	}
+	equals("two\n", read(start.Add(72*time.Hour), start.Add(73*time.Hour)), t)
+	equals("two\nthree\n", read(start.Add(72*time.Hour), start.Add(120*time.Hour)), t)
+	equals("three\nfour\n", read(start.Add(130*time.Hour), start.Add(1000*time.Hour)), t)
+	equals("one\ntwo\nthree\nfour\n", read(time.Time{}, start.Add(1000*time.Hour)), t)
+}
+
+func TestReadRangeLines(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReadRangeLines", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+	}
+	defer l.Close()
+	_, err := l.Write([]byte("2024-05-04 14:01:00 early\n" +
+		"2024-05-04 14:02:00 first\n" +
+		"  continued\n" +
+		"2024-05-04 14:20:00 last\n" +
+		"2024-05-04 14:21:00 late\n" +
+		"  continued\n"))
+	isNil(err, t)
+
+	from := time.Date(2024, 5, 4, 14, 2, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 4, 14, 20, 0, 0, time.UTC)
+	r, err := l.ReadRange(from, to, &RangeOptions{TimeLayout: "2006-01-02 15:04:05"})
+	isNil(err, t)
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	isNil(err, t)
+	equals("2024-05-04 14:02:00 first\n  continued\n2024-05-04 14:20:00 last\n", string(b), t)
+}
+
+func TestReadRangeCompressedOrder(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestReadRangeCompressedOrder", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("one\n"))
+	isNil(err, t)
+	newFakeTime()
+	rotatedAt := fakeTime()
+	isNil(l.Rotate(), t)
+	_, err = l.Write([]byte("two\n"))
+	isNil(err, t)
+
+	// compression keeps the rotation time of the backup.
+	info, err := os.Stat(backupFileWithOrder(dir, 1) + compressSuffix)
+	isNil(err, t)
+	equals(rotatedAt.Unix(), info.ModTime().Unix(), t)
+
+	r, err := l.ReadRange(rotatedAt.Add(-time.Hour), rotatedAt.Add(time.Hour), nil)
+	isNil(err, t)
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	isNil(err, t)
+	equals("one\ntwo\n", string(b), t)
+}
@@ -0,0 +1,225 @@
+package logrotate
+
+import (
+	"time"
+)
+
+// RetentionPolicy decides which backups are kept, compressed and removed
+// after every rotation. Set it as the Retention of a Logger to replace the
+// rules of MaxBackups, MaxAge and Compress.
+type RetentionPolicy interface {
+	// Plan is given the backups of the Logger, newest first, and the
+	// current time of its Clock. Backups that are in neither Keep nor
+	// Remove are kept. A backup and its compressed or encrypted version may
+	// both be listed while it is being converted.
+	Plan(backups []BackupInfo, now time.Time) RetentionPlan
+}
+
+// RetentionPlan is the outcome of a RetentionPolicy. Compress should only
+// list kept backups that are neither compressed nor encrypted yet; others
+// are ignored.
+type RetentionPlan struct {
+	Keep     []BackupInfo
+	Compress []BackupInfo
+	Remove   []BackupInfo
+}
+
+// LimitPolicy is the RetentionPolicy of a Logger without a Retention, built
+// from its MaxBackups, MaxAge and Compress: the MaxBackups newest backups
+// younger than MaxAge are kept.
+type LimitPolicy struct {
+	// MaxBackups is the maximum number of backups to keep. 0 keeps all of
+	// them.
+	MaxBackups int
+
+	// MaxAge is the maximum age of the backups to keep. 0 keeps all of
+	// them.
+	MaxAge time.Duration
+
+	// MaxAgeDays, if set, is the number of calendar days of Location that
+	// backups are kept for after the day they were made, in place of
+	// MaxAge.
+	MaxAgeDays int
+
+	// Location is the time zone MaxAgeDays are counted in. It defaults to
+	// UTC.
+	Location *time.Location
+
+	// Compress determines if the kept backups are compressed.
+	Compress bool
+}
+
+// Plan implements RetentionPolicy.
+func (p LimitPolicy) Plan(backups []BackupInfo, now time.Time) RetentionPlan {
+	var cutoff time.Time
+	switch {
+	case p.MaxAgeDays > 0:
+		loc := p.Location
+		if loc == nil {
+			loc = time.UTC
+		}
+		cutoff = startOfDay(now.In(loc)).AddDate(0, 0, -p.MaxAgeDays)
+	case p.MaxAge > 0:
+		cutoff = now.Add(-p.MaxAge)
+	}
+
+	var plan RetentionPlan
+	preserved := make(map[string]bool)
+	for _, b := range backups {
+		// Only count the uncompressed log file or the compressed log
+		// file, not both.
+		preserved[baseBackupName(b.Name)] = true
+		switch {
+		case p.MaxBackups > 0 && len(preserved) > p.MaxBackups,
+			b.Time.Before(cutoff):
+			plan.Remove = append(plan.Remove, b)
+		default:
+			plan.Keep = append(plan.Keep, b)
+		}
+	}
+	if p.Compress {
+		plan.Compress = uncompressed(plan.Keep)
+	}
+	return plan
+}
+
+// GFSPolicy is a grandfather-father-son RetentionPolicy: recent backups are
+// all kept, then the newest backup of every day, then of every week, then of
+// every month, for a number of each. For example, keeping every backup for 24
+// hours, one per day for 30 days and one per week for a year is
+//
+//	GFSPolicy{All: 24 * time.Hour, Daily: 30, Weekly: 52}
+//
+// Days, weeks, which start on Monday, and months are those of Location.
+type GFSPolicy struct {
+	// All is the age under which every backup is kept.
+	All time.Duration
+
+	// Daily is the number of days, counting today, for which the newest
+	// backup of the day is kept.
+	Daily int
+
+	// Weekly is the number of weeks, counting this one, for which the
+	// newest backup of the week is kept.
+	Weekly int
+
+	// Monthly is the number of months, counting this one, for which the
+	// newest backup of the month is kept.
+	Monthly int
+
+	// Location is the time zone days start in. It defaults to UTC.
+	Location *time.Location
+
+	// Compress determines if the kept backups are compressed.
+	Compress bool
+}
+
+// Plan implements RetentionPolicy.
+func (p GFSPolicy) Plan(backups []BackupInfo, now time.Time) RetentionPlan {
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+	today := startOfDay(now)
+	thisWeek := startOfWeek(today)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+
+	// seen holds the periods whose newest backup was already found.
+	seen := make(map[string]bool)
+	// kept holds the base names of the backups to keep, so that a backup
+	// and its compressed version are treated alike.
+	kept := make(map[string]bool)
+	for _, b := range backups {
+		base := baseBackupName(b.Name)
+		t := b.Time.In(loc)
+		day := startOfDay(t)
+		week := startOfWeek(day)
+		month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+
+		keep := now.Sub(t) < p.All
+		if key := "d" + day.Format("2006-01-02"); !seen[key] {
+			seen[key] = true
+			keep = keep || daysBetween(day, today) < p.Daily
+		}
+		if key := "w" + week.Format("2006-01-02"); !seen[key] {
+			seen[key] = true
+			keep = keep || daysBetween(week, thisWeek)/7 < p.Weekly
+		}
+		if key := "m" + month.Format("2006-01"); !seen[key] {
+			seen[key] = true
+			months := (thisMonth.Year()-month.Year())*12 + int(thisMonth.Month()-month.Month())
+			keep = keep || months < p.Monthly
+		}
+		if keep {
+			kept[base] = true
+		}
+	}
+
+	var plan RetentionPlan
+	for _, b := range backups {
+		if kept[baseBackupName(b.Name)] {
+			plan.Keep = append(plan.Keep, b)
+		} else {
+			plan.Remove = append(plan.Remove, b)
+		}
+	}
+	if p.Compress {
+		plan.Compress = uncompressed(plan.Keep)
+	}
+	return plan
+}
+
+// startOfDay returns the midnight starting the day of t, in its location.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the Monday starting the week of day, a midnight.
+func startOfWeek(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// daysBetween returns the number of calendar days from the midnight from to
+// the midnight to, regardless of daylight saving time changes.
+func daysBetween(from, to time.Time) int {
+	// Noon UTC of the same dates is free of time zone offsets.
+	f := time.Date(from.Year(), from.Month(), from.Day(), 12, 0, 0, 0, time.UTC)
+	t := time.Date(to.Year(), to.Month(), to.Day(), 12, 0, 0, 0, time.UTC)
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// uncompressed returns the backups that are neither compressed nor
+// encrypted.
+func uncompressed(backups []BackupInfo) []BackupInfo {
+	var out []BackupInfo
+	for _, b := range backups {
+		if !b.Compressed && !b.Encrypted {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// retention returns the RetentionPolicy of the Logger. The caller must hold
+// l.mu.
+func (l *Logger) retention() (RetentionPolicy, error) {
+	if l.Retention != nil {
+		return l.Retention, nil
+	}
+	p := LimitPolicy{
+		MaxBackups: l.MaxBackups,
+		MaxAge:     l.maxAge(),
+		Compress:   l.Compress,
+	}
+	if l.CalendarDays && l.MaxAgeDuration == 0 && l.MaxAge > 0 {
+		loc, err := l.location()
+		if err != nil {
+			return nil, err
+		}
+		p.MaxAgeDays = l.MaxAge
+		p.Location = loc
+	}
+	return p, nil
+}
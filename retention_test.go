@@ -0,0 +1,108 @@
+package logrotate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// backupsEvery returns n backups made every d up to newest, newest first.
+func backupsEvery(newest time.Time, d time.Duration, n int) []BackupInfo {
+	var backups []BackupInfo
+	for i := 0; i < n; i++ {
+		t := newest.Add(-time.Duration(i) * d)
+		backups = append(backups, BackupInfo{Name: fmt.Sprintf("foo-%s.log", t.Format(backupTimeFormat)), Time: t})
+	}
+	return backups
+}
+
+func TestLimitPolicy(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	backups := backupsEvery(now.Add(-time.Hour), 24*time.Hour, 5)
+	// the newest backup is being compressed.
+	gz := backups[0]
+	gz.Name += compressSuffix
+	gz.Compressed = true
+	backups = append([]BackupInfo{gz}, backups...)
+
+	plan := LimitPolicy{MaxBackups: 3, Compress: true}.Plan(backups, now)
+	equals(4, len(plan.Keep), t)
+	equals(2, len(plan.Remove), t)
+	equals(3, len(plan.Compress), t)
+	equals(backups[1], plan.Compress[0], t)
+
+	plan = LimitPolicy{MaxAge: 48 * time.Hour}.Plan(backups, now)
+	equals(3, len(plan.Keep), t)
+	equals(3, len(plan.Remove), t)
+	equals(0, len(plan.Compress), t)
+}
+
+func TestGFSPolicy(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	backups := backupsEvery(now.Add(-time.Hour), 6*time.Hour, 4*400)
+	p := GFSPolicy{All: 24 * time.Hour, Daily: 30, Weekly: 52}
+	plan := p.Plan(backups, now)
+	equals(len(backups), len(plan.Keep)+len(plan.Remove), t)
+
+	perDay := make(map[string]int)
+	perWeek := make(map[string]int)
+	for _, b := range plan.Keep {
+		age := now.Sub(b.Time)
+		if age < 24*time.Hour {
+			continue
+		}
+		assert(age < 53*7*24*time.Hour, t, "expected %s to be removed", b.Name)
+		day := startOfDay(b.Time)
+		perDay[day.Format("2006-01-02")]++
+		perWeek[startOfWeek(day).Format("2006-01-02")]++
+		// the newest backup of the day is the one at 23:00.
+		assert(b.Time.Hour() == 23, t, "expected %s to be the newest of its day", b.Name)
+	}
+	for _, n := range perDay {
+		equals(1, n, t)
+	}
+	// one per day for 30 days, then one per week, including the current
+	// week, for 52 weeks.
+	// yesterday's newest backup is less than 24 hours old.
+	for i := 2; i < 30; i++ {
+		day := startOfDay(now).AddDate(0, 0, -i)
+		equals(1, perDay[day.Format("2006-01-02")], t)
+	}
+	thisWeek := startOfWeek(startOfDay(now))
+	for i := 5; i < 52; i++ {
+		week := thisWeek.AddDate(0, 0, -7*i)
+		equals(1, perWeek[week.Format("2006-01-02")], t)
+	}
+	equals(0, perWeek[thisWeek.AddDate(0, 0, -7*52).Format("2006-01-02")], t)
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	dir := "/logs"
+	isNil(fsys.MkdirAll(dir, 0755), t)
+	now := fakeTime()
+	old := backupsEvery(now.Add(-time.Hour), 24*time.Hour, 10)
+	for _, b := range old {
+		isNil(writeFile(fsys, dir+"/foobar-"+b.Time.UTC().Format(backupTimeFormat)+".log", []byte("old"), 0644), t)
+	}
+
+	l := &Logger{
+		Filename:           logFile(dir),
+		FilenameTimeFormat: backupTimeFormat,
+		Retention:          GFSPolicy{Daily: 3, Compress: true},
+		FS:                 fsys,
+	}
+	defer l.Close()
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	// one backup of each of the last three days is left, compressed.
+	equals(3, len(backups), t)
+	for _, b := range backups {
+		equals(true, b.Compressed, t)
+	}
+}
@@ -0,0 +1,228 @@
+package logrotate
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// KeyPlaceholder is replaced by the key in the file name template of a Router.
+const KeyPlaceholder = "{key}"
+
+// Router writes to one log file per key, such as one file per tenant. The
+// files are named from a template and share one configuration; each of them
+// is rotated and cleaned up on its own like a Logger. Writes to different
+// keys don't wait for each other.
+//
+// To bound the number of open file descriptors, at most MaxOpen files are
+// kept open. When a write needs another file, the least recently used one
+// that is not being written to is closed, and it is reopened the next time
+// its key is written to. Reopening it does not clean up old log files again,
+// which only happens on rotations and when the file is first opened.
+type Router struct {
+	template string
+	base     *Logger
+	maxOpen  int
+
+	mu      sync.Mutex
+	loggers map[string]*routerEntry
+	// open holds the entries with an open file, most recently used first.
+	open *list.List
+}
+
+// routerEntry is the Logger of a key and its position in the LRU list.
+type routerEntry struct {
+	logger *Logger
+	elem   *list.Element
+	// refs is the number of writes and rotations in progress, which keep
+	// the file from being closed.
+	refs int
+	// gen counts the times the entry was put in the LRU list, to tell
+	// whether it was used again since it was removed from it.
+	gen int
+}
+
+// idleEntry is an entry removed from the LRU list, with its gen at the time.
+type idleEntry struct {
+	e   *routerEntry
+	gen int
+}
+
+// NewRouter returns a Router writing to files named after template, in which
+// every occurrence of KeyPlaceholder is replaced by the key. Every file gets
+// all the settings of base, including its Encrypter and FS, except for its
+// Filename; base itself is not written to. If maxOpen is 0 or less, files are
+// never closed for being idle.
+func NewRouter(template string, base *Logger, maxOpen int) *Router {
+	return &Router{
+		template: template,
+		base:     base,
+		maxOpen:  maxOpen,
+		loggers:  make(map[string]*routerEntry),
+		open:     list.New(),
+	}
+}
+
+// Writer returns an io.Writer writing to the log file of key.
+func (r *Router) Writer(key string) io.Writer {
+	return routerWriter{r: r, key: key}
+}
+
+// routerWriter is the io.Writer returned by Router.Writer.
+type routerWriter struct {
+	r   *Router
+	key string
+}
+
+func (w routerWriter) Write(p []byte) (int, error) {
+	return w.r.Write(w.key, p)
+}
+
+// Write writes p to the log file of key, opening it if needed.
+func (r *Router) Write(key string, p []byte) (int, error) {
+	e, err := r.acquire(key)
+	if err != nil {
+		return 0, err
+	}
+	defer r.release(e)
+	return e.logger.Write(p)
+}
+
+// Rotate rotates the log file of key.
+func (r *Router) Rotate(key string) error {
+	e, err := r.acquire(key)
+	if err != nil {
+		return err
+	}
+	defer r.release(e)
+	return e.logger.Rotate()
+}
+
+// Close closes all the open log files.
+func (r *Router) Close() error {
+	r.mu.Lock()
+	var loggers []*Logger
+	for r.open.Len() > 0 {
+		e := r.open.Remove(r.open.Front()).(*routerEntry)
+		e.elem = nil
+		loggers = append(loggers, e.logger)
+	}
+	r.mu.Unlock()
+
+	var err error
+	for _, l := range loggers {
+		if errClose := l.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
+
+// OpenFiles returns the number of log files currently open.
+func (r *Router) OpenFiles() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.open.Len()
+}
+
+// acquire returns the entry of key, marked as in use and as the most recently
+// used one, creating its Logger if needed. The entry must be released.
+func (r *Router) acquire(key string) (*routerEntry, error) {
+	r.mu.Lock()
+	e, err := r.entry(key)
+	if err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+	e.refs++
+	if e.elem != nil {
+		r.open.MoveToFront(e.elem)
+	} else {
+		e.elem = r.open.PushFront(e)
+		e.gen++
+	}
+	idle := r.trim()
+	r.mu.Unlock()
+
+	r.closeIdle(idle)
+	return e, nil
+}
+
+// release marks the entry as no longer in use by the caller of acquire.
+func (r *Router) release(e *routerEntry) {
+	r.mu.Lock()
+	e.refs--
+	idle := r.trim()
+	r.mu.Unlock()
+
+	r.closeIdle(idle)
+}
+
+// entry returns the entry of key, creating its Logger if needed. The caller
+// must hold r.mu.
+func (r *Router) entry(key string) (*routerEntry, error) {
+	if e, ok := r.loggers[key]; ok {
+		return e, nil
+	}
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return nil, fmt.Errorf("invalid log file key %q", key)
+	}
+	l := r.base.derive(strings.ReplaceAll(r.template, KeyPlaceholder, key))
+	e := &routerEntry{logger: l}
+	r.loggers[key] = e
+	return e, nil
+}
+
+// trim removes the least recently used entries that are not in use from the
+// LRU list while there are more than maxOpen, and returns them to be closed
+// once r.mu is released. The caller must hold r.mu.
+func (r *Router) trim() []idleEntry {
+	if r.maxOpen <= 0 {
+		return nil
+	}
+	var idle []idleEntry
+	for elem := r.open.Back(); elem != nil && r.open.Len() > r.maxOpen; {
+		prev := elem.Prev()
+		if e := elem.Value.(*routerEntry); e.refs == 0 {
+			r.open.Remove(elem)
+			e.elem = nil
+			idle = append(idle, idleEntry{e, e.gen})
+		}
+		elem = prev
+	}
+	return idle
+}
+
+// closeIdle closes the files of the idle entries, unless they were used
+// again since they were removed from the LRU list.
+func (r *Router) closeIdle(idle []idleEntry) {
+	for _, c := range idle {
+		c.e.logger.closeIdle(func() bool {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return c.e.elem == nil && c.e.refs == 0 && c.e.gen == c.gen
+		})
+	}
+}
+
+// closeIdle closes the file like Close, for a Router, if evicted, called with
+// l.mu held, reports that the Router still considers it idle. The old log
+// files are not cleaned up when the file is reopened, as they were when it
+// was first opened.
+func (l *Logger) closeIdle(evicted func() bool) error {
+	l.mu.Lock()
+	if !evicted() {
+		l.mu.Unlock()
+		return nil
+	}
+	l.idle = true
+	err := l.close()
+	done := l.stopJanitor()
+	l.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	return err
+}
@@ -0,0 +1,182 @@
+package logrotate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestRouter", t)
+	defer os.RemoveAll(dir)
+
+	r := NewRouter(filepath.Join(dir, "tenant-{key}.log"), &Logger{MaxBytes: 10}, 2)
+	defer r.Close()
+
+	for _, key := range []string{"a", "b", "c", "a"} {
+		_, err := r.Writer(key).Write([]byte(key + "!"))
+		isNil(err, t)
+		assert(r.OpenFiles() <= 2, t, "expected at most 2 open files, got %d", r.OpenFiles())
+	}
+
+	existsWithContent(filepath.Join(dir, "tenant-a.log"), []byte("a!a!"), t)
+	existsWithContent(filepath.Join(dir, "tenant-b.log"), []byte("b!"), t)
+	existsWithContent(filepath.Join(dir, "tenant-c.log"), []byte("c!"), t)
+
+	// Each key is rotated on its own once it reaches MaxBytes.
+	_, err := r.Write("b", []byte("bbbbbbbbb!"))
+	isNil(err, t)
+	existsWithContent(filepath.Join(dir, "tenant-b.log.1"), []byte("b!"), t)
+	existsWithContent(filepath.Join(dir, "tenant-b.log"), []byte("bbbbbbbbb!"), t)
+	fileCount(dir, 4, t)
+}
+
+func TestRouterRetentionPerKey(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestRouterRetentionPerKey", t)
+	defer os.RemoveAll(dir)
+
+	r := NewRouter(filepath.Join(dir, "{key}.log"), &Logger{MaxBackups: 1}, 1)
+	defer r.Close()
+
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"a", "b"} {
+			_, err := r.Write(key, []byte("boo!"))
+			isNil(err, t)
+			isNil(r.Rotate(key), t)
+		}
+	}
+	equals(1, r.OpenFiles(), t)
+
+	// Every key keeps its active file and a single backup.
+	exists(filepath.Join(dir, "a.log.3"), t)
+	exists(filepath.Join(dir, "b.log.3"), t)
+	fileCount(dir, 4, t)
+}
+
+func TestRouterInvalidKey(t *testing.T) {
+	r := NewRouter(filepath.Join(os.TempDir(), "{key}.log"), &Logger{}, 0)
+	defer r.Close()
+	_, err := r.Write("../escape", []byte("boo!"))
+	notNil(err, t)
+	_, err = r.Write("", []byte("boo!"))
+	notNil(err, t)
+}
+
+func TestRouterInheritsLogger(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	enc, err := NewAESGCM("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	isNil(err, t)
+	r := NewRouter("/logs/{key}.log", &Logger{Encrypter: enc, FS: fsys}, 0)
+	defer r.Close()
+
+	_, err = r.Write("a", []byte("boo!"))
+	isNil(err, t)
+	isNil(r.Rotate("a"), t)
+	memExistsWithContent(fsys, "/logs/a.log", []byte{}, t)
+	_, err = fsys.Stat("/logs/a.log.1" + encryptSuffix)
+	isNil(err, t)
+	_, err = os.Stat("/logs")
+	assert(os.IsNotExist(err), t, "expected nothing on disk, got %v", err)
+}
+
+func TestRouterConcurrentKeys(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	fsys.Fail = func(op, name string) error {
+		if op == "write" && name == "/logs/a.log" {
+			once.Do(func() {
+				close(started)
+				<-release
+			})
+		}
+		return nil
+	}
+	r := NewRouter("/logs/{key}.log", &Logger{FS: fsys}, 1)
+	defer r.Close()
+
+	done := make(chan error)
+	go func() {
+		_, err := r.Write("a", []byte("a!"))
+		done <- err
+	}()
+	<-started
+
+	// a write to another key goes through while the first one is blocked,
+	// and does not close the file being written to.
+	_, err := r.Write("b", []byte("b!"))
+	isNil(err, t)
+	close(release)
+	isNil(<-done, t)
+	equals(1, r.OpenFiles(), t)
+	memExistsWithContent(fsys, "/logs/a.log", []byte("a!"), t)
+	memExistsWithContent(fsys, "/logs/b.log", []byte("b!"), t)
+}
+
+func TestRouterReopenSkipsCleanup(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	var readDirs atomic.Int32
+	fsys.Fail = func(op, name string) error {
+		if op == "readdir" {
+			readDirs.Add(1)
+		}
+		return nil
+	}
+	r := NewRouter("/logs/{key}.log", &Logger{FS: fsys, MaxBackups: 1}, 1)
+	defer r.Close()
+
+	for _, key := range []string{"a", "b"} {
+		_, err := r.Write(key, []byte(key+"!"))
+		isNil(err, t)
+	}
+	// files evicted for being idle are reopened without scanning the
+	// directory again.
+	before := readDirs.Load()
+	for i := 0; i < 10; i++ {
+		for _, key := range []string{"a", "b"} {
+			_, err := r.Write(key, []byte(key+"!"))
+			isNil(err, t)
+		}
+	}
+	equals(before, readDirs.Load(), t)
+	memExistsWithContent(fsys, "/logs/a.log", bytes.Repeat([]byte("a!"), 11), t)
+}
+
+func TestRouterStaleEviction(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	r := NewRouter("/logs/{key}.log", &Logger{FS: fsys}, 1)
+	defer r.Close()
+
+	_, err := r.Write("a", []byte("a!"))
+	isNil(err, t)
+	// b is acquired, which evicts a, but a is written to again before its
+	// file gets closed.
+	r.mu.Lock()
+	b, err := r.entry("b")
+	isNil(err, t)
+	b.elem = r.open.PushFront(b)
+	b.gen++
+	idle := r.trim()
+	r.mu.Unlock()
+	equals(1, len(idle), t)
+
+	_, err = r.Write("a", []byte("a!"))
+	isNil(err, t)
+	r.closeIdle(idle)
+
+	a := r.loggers["a"]
+	equals(1, r.OpenFiles(), t)
+	assert(a.elem != nil, t, "expected a to be listed as open")
+	assert(a.logger.file != nil, t, "expected the file of a to be open")
+	equals(false, a.logger.idle, t)
+}
@@ -0,0 +1,97 @@
+package logrotate
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// signalAction is what HandleSignals does when a signal is received.
+type signalAction int
+
+const (
+	rotateAction signalAction = iota
+	reopenAction
+)
+
+// signalConfig holds the settings built by SignalOptions.
+type signalConfig struct {
+	actions map[os.Signal]signalAction
+	onError func(os.Signal, error)
+}
+
+// SignalOption configures HandleSignals.
+type SignalOption func(*signalConfig)
+
+// RotateOn makes HandleSignals call Rotate when one of sigs is received.
+func RotateOn(sigs ...os.Signal) SignalOption {
+	return func(c *signalConfig) {
+		for _, sig := range sigs {
+			c.actions[sig] = rotateAction
+		}
+	}
+}
+
+// ReopenOn makes HandleSignals call Reopen when one of sigs is received. This
+// is what a postrotate script of the system logrotate expects after it has
+// moved the log file itself.
+func ReopenOn(sigs ...os.Signal) SignalOption {
+	return func(c *signalConfig) {
+		for _, sig := range sigs {
+			c.actions[sig] = reopenAction
+		}
+	}
+}
+
+// OnSignalError sets the function called with the received signal when the
+// Rotate or Reopen it triggered fails. Errors are dropped if it is not set.
+func OnSignalError(fn func(os.Signal, error)) SignalOption {
+	return func(c *signalConfig) {
+		c.onError = fn
+	}
+}
+
+// HandleSignals registers handlers for the signals given by opts and rotates
+// or reopens the log file whenever one of them is received. Handling runs in
+// its own goroutine and stops, unregistering the handlers, when ctx is done.
+//
+// For example, to rotate on SIGHUP and reopen on SIGUSR1:
+//
+//	l.HandleSignals(ctx, logrotate.RotateOn(syscall.SIGHUP), logrotate.ReopenOn(syscall.SIGUSR1))
+func (l *Logger) HandleSignals(ctx context.Context, opts ...SignalOption) {
+	c := &signalConfig{actions: make(map[os.Signal]signalAction)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(c.actions) == 0 {
+		return
+	}
+
+	sigs := make([]os.Signal, 0, len(c.actions))
+	for sig := range c.actions {
+		sigs = append(sigs, sig)
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				var err error
+				switch c.actions[sig] {
+				case rotateAction:
+					err = l.Rotate()
+				case reopenAction:
+					err = l.Reopen()
+				}
+				if err != nil && c.onError != nil {
+					c.onError(sig, err)
+				}
+			}
+		}
+	}()
+}
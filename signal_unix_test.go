@@ -0,0 +1,48 @@
+//go:build unix
+
+package logrotate
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestHandleSignals(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestHandleSignals", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.HandleSignals(ctx, RotateOn(syscall.SIGHUP), ReopenOn(syscall.SIGUSR1))
+
+	err = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	isNil(err, t)
+	waitFor(func() bool { _, err := os.Stat(backupFileWithOrder(dir, 1)); return err == nil }, t)
+	existsWithContent(backupFileWithOrder(dir, 1), b, t)
+
+	// Simulate an external tool moving the file before signaling.
+	moved := filename + ".moved"
+	err = os.Rename(filename, moved)
+	isNil(err, t)
+	err = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	isNil(err, t)
+	waitFor(func() bool { _, err := os.Stat(filename); return err == nil }, t)
+
+	b2 := []byte("foo!")
+	_, err = l.Write(b2)
+	isNil(err, t)
+	existsWithContent(filename, b2, t)
+	existsWithContent(moved, []byte{}, t)
+}
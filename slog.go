@@ -0,0 +1,194 @@
+package logrotate
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+// LevelRoute sends the records at or above Level to their own log file. The
+// file is named after the main log file with Name inserted before the
+// extension, so the route `error` of `app.log` writes to `app.error.log`.
+type LevelRoute struct {
+	Level slog.Leveler
+	Name  string
+}
+
+// HandlerOptions configures a Handler.
+type HandlerOptions struct {
+	// HandlerOptions is passed to the handlers formatting the records. Its
+	// Level is the minimum level of the records handled at all.
+	slog.HandlerOptions
+
+	// Routes lists the extra log files records are routed to. A record goes
+	// to the route with the highest Level it reaches, or to the main log
+	// file if it reaches none.
+	Routes []LevelRoute
+
+	// AlsoMain determines if routed records are written to the main log file
+	// as well.
+	AlsoMain bool
+
+	// NewHandler creates the handler formatting records for a log file. It
+	// defaults to slog.NewJSONHandler.
+	NewHandler func(w io.Writer, opts *slog.HandlerOptions) slog.Handler
+}
+
+// Handler is a slog.Handler writing records to a main Logger and to extra
+// Loggers chosen by level. All the Loggers share the configuration of the
+// main one, including its Encrypter, FS and Clock, and Rotate, Reconfigure,
+// WatchConfig and Close apply to all of them. Reconfiguring the main Logger
+// itself leaves the others as they are.
+type Handler struct {
+	loggers  []*Logger
+	handlers []slog.Handler
+	routes   []slog.Leveler
+	// names holds the name of each route, after the main Logger.
+	names    []string
+	alsoMain bool
+	level    slog.Leveler
+}
+
+// ensure we always implement slog.Handler
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler returns a Handler writing to l and to one Logger per route of
+// opts, which may be nil.
+func NewHandler(l *Logger, opts *HandlerOptions) *Handler {
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+	newHandler := opts.NewHandler
+	if newHandler == nil {
+		newHandler = func(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+			return slog.NewJSONHandler(w, opts)
+		}
+	}
+	level := opts.Level
+	if level == nil {
+		level = slog.LevelInfo
+	}
+
+	h := &Handler{
+		loggers:  []*Logger{l},
+		handlers: []slog.Handler{newHandler(l, &opts.HandlerOptions)},
+		routes:   []slog.Leveler{nil},
+		names:    []string{""},
+		alsoMain: opts.AlsoMain,
+		level:    level,
+	}
+	for _, route := range opts.Routes {
+		rl := l.derive(routeFilename(l.filename(), route.Name))
+		h.loggers = append(h.loggers, rl)
+		h.handlers = append(h.handlers, newHandler(rl, &opts.HandlerOptions))
+		h.routes = append(h.routes, route.Level)
+		h.names = append(h.names, route.Name)
+	}
+	return h
+}
+
+// routeFilename inserts name before the extension of filename.
+func routeFilename(filename, name string) string {
+	ext := filepath.Ext(filename)
+	return filename[:len(filename)-len(ext)] + "." + name + ext
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler, writing r to the log file of its route.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	route := 0
+	for i := 1; i < len(h.routes); i++ {
+		if r.Level >= h.routes[i].Level() && (route == 0 || h.routes[i].Level() > h.routes[route].Level()) {
+			route = i
+		}
+	}
+	err := h.handlers[route].Handle(ctx, r)
+	if route != 0 && h.alsoMain {
+		if errMain := h.handlers[0].Handle(ctx, r); err == nil {
+			err = errMain
+		}
+	}
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithAttrs(attrs) })
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithGroup(name) })
+}
+
+// with returns a copy of h sharing its Loggers, with fn applied to each of
+// the formatting handlers.
+func (h *Handler) with(fn func(slog.Handler) slog.Handler) *Handler {
+	h2 := *h
+	h2.handlers = make([]slog.Handler, len(h.handlers))
+	for i, inner := range h.handlers {
+		h2.handlers[i] = fn(inner)
+	}
+	return &h2
+}
+
+// Loggers returns the main Logger followed by the Logger of each route.
+func (h *Handler) Loggers() []*Logger {
+	return append([]*Logger(nil), h.loggers...)
+}
+
+// Rotate rotates all the log files of the Handler.
+func (h *Handler) Rotate() error {
+	var err error
+	for _, l := range h.loggers {
+		if errRotate := l.Rotate(); err == nil {
+			err = errRotate
+		}
+	}
+	return err
+}
+
+// Reconfigure applies cfg to the main Logger, and to the Logger of every route
+// with its file named after the main one. See Logger.Reconfigure.
+func (h *Handler) Reconfigure(cfg Config) error {
+	main := h.loggers[0]
+	if err := main.Reconfigure(cfg); err != nil {
+		return err
+	}
+	main.mu.Lock()
+	filename := main.filename()
+	main.mu.Unlock()
+
+	var err error
+	for i, l := range h.loggers[1:] {
+		rcfg := cfg
+		rcfg.Filename = routeFilename(filename, h.names[i+1])
+		if errReconfigure := l.Reconfigure(rcfg); err == nil {
+			err = errReconfigure
+		}
+	}
+	return err
+}
+
+// WatchConfig is like Logger.WatchConfig, but applies the config file to all
+// the Loggers of the Handler with Reconfigure.
+func (h *Handler) WatchConfig(ctx context.Context, path string, interval time.Duration, onError func(error)) error {
+	return watchConfig(ctx, path, interval, onError, h.loggers[0].clock(), h.Reconfigure)
+}
+
+// Close closes all the log files of the Handler.
+func (h *Handler) Close() error {
+	var err error
+	for _, l := range h.loggers {
+		if errClose := l.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
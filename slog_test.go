@@ -0,0 +1,145 @@
+package logrotate
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandlerRoutes(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestHandlerRoutes", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+	}
+	h := NewHandler(l, &HandlerOptions{
+		HandlerOptions: slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		},
+		Routes: []LevelRoute{
+			{Level: slog.LevelWarn, Name: "warn"},
+			{Level: slog.LevelError, Name: "error"},
+		},
+		AlsoMain: true,
+		NewHandler: func(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+			return slog.NewTextHandler(w, opts)
+		},
+	})
+	defer h.Close()
+
+	log := slog.New(h).With("app", "foo")
+	log.Debug("debug")
+	log.Warn("warn")
+	log.Error("error")
+
+	debugLine := []byte("level=DEBUG msg=debug app=foo\n")
+	warnLine := []byte("level=WARN msg=warn app=foo\n")
+	errorLine := []byte("level=ERROR msg=error app=foo\n")
+	existsWithContent(logFile(dir), bytes.Join([][]byte{debugLine, warnLine, errorLine}, nil), t)
+	existsWithContent(filepath.Join(dir, "foobar.warn.log"), warnLine, t)
+	existsWithContent(filepath.Join(dir, "foobar.error.log"), errorLine, t)
+	fileCount(dir, 3, t)
+}
+
+func TestHandlerRotate(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestHandlerRotate", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:   logFile(dir),
+		MaxBackups: 1,
+	}
+	h := NewHandler(l, &HandlerOptions{
+		Routes: []LevelRoute{{Level: slog.LevelError, Name: "error"}},
+	})
+	defer h.Close()
+	equals(2, len(h.Loggers()), t)
+	equals(1, h.Loggers()[1].MaxBackups, t)
+
+	log := slog.New(h)
+	log.Info("info")
+	log.Error("error")
+
+	err := h.Rotate()
+	isNil(err, t)
+	exists(backupFileWithOrder(dir, 1), t)
+	exists(filepath.Join(dir, "foobar.error.log.1"), t)
+	fileCount(dir, 4, t)
+}
+
+func TestHandlerReconfigure(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	l := &Logger{
+		Filename: logFile("/logs"),
+		FS:       fsys,
+	}
+	h := NewHandler(l, &HandlerOptions{
+		Routes: []LevelRoute{{Level: slog.LevelError, Name: "error"}},
+	})
+	defer h.Close()
+	log := slog.New(h)
+	log.Error("before")
+
+	cfg := l.Config()
+	cfg.Filename = "/logs/app.log"
+	cfg.MaxBackups = 1
+	isNil(h.Reconfigure(cfg), t)
+
+	// the route Logger follows the main one, to a file named after it.
+	rl := h.Loggers()[1]
+	equals(1, rl.Config().MaxBackups, t)
+	equals("/logs/app.error.log", rl.Config().Filename, t)
+	log.Error("after")
+	b, err := readFile(fsys, "/logs/app.error.log")
+	isNil(err, t)
+	assert(bytes.Contains(b, []byte(`"msg":"after"`)), t, "expected the record in the new error log, got %q", b)
+	b, err = readFile(fsys, "/logs/foobar.error.log")
+	isNil(err, t)
+	assert(!bytes.Contains(b, []byte(`"msg":"after"`)), t, "expected the record out of the old error log, got %q", b)
+}
+
+func TestHandlerRoutesInheritLogger(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	enc, err := NewAESGCM("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	isNil(err, t)
+	l := &Logger{
+		Filename:  logFile("/logs"),
+		Encrypter: enc,
+		FS:        fsys,
+	}
+	h := NewHandler(l, &HandlerOptions{
+		Routes: []LevelRoute{{Level: slog.LevelError, Name: "error"}},
+	})
+	defer h.Close()
+	rl := h.Loggers()[1]
+	assert(rl.Encrypter == Encrypter(enc), t, "expected the route Logger to inherit the Encrypter")
+	assert(rl.FS == FS(fsys), t, "expected the route Logger to inherit the FS")
+
+	slog.New(h).Error("secret")
+	isNil(h.Rotate(), t)
+
+	// the error log and its backup are on the MemFS, and the backup is
+	// encrypted.
+	_, err = fsys.Stat("/logs/foobar.error.log")
+	isNil(err, t)
+	_, err = fsys.Stat("/logs/foobar.error.log.1" + encryptSuffix)
+	isNil(err, t)
+	_, err = fsys.Stat("/logs/foobar.error.log.1")
+	assert(os.IsNotExist(err), t, "expected no plaintext backup, got %v", err)
+	_, err = os.Stat("/logs")
+	assert(os.IsNotExist(err), t, "expected nothing on disk, got %v", err)
+}
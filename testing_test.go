@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 // identifier can be used as a name of folder(s) etc.
@@ -36,6 +37,15 @@ func assertUp(condition bool, t testing.TB, caller int, msg string, v ...interfa
 	}
 }
 
+// waitFor polls cond until it is true, failing the test after a few seconds.
+func waitFor(cond func() bool, t testing.TB) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		assertUp(time.Now().Before(deadline), t, 1, "timed out waiting for condition")
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
 // equals tests that the two values are equal according to reflect.DeepEqual.
 func equals(exp, act interface{}, t testing.TB) {
 	equalsUp(exp, act, t, 1)
@@ -0,0 +1,43 @@
+package logrotate
+
+import (
+	"fmt"
+	"time"
+)
+
+// location returns the time zone backups are named and expired in: Location
+// if set, else TimeZone, else the local time zone if LocalTime is set, else
+// UTC. The caller must hold l.mu.
+func (l *Logger) location() (*time.Location, error) {
+	switch {
+	case l.Location != nil:
+		return l.Location, nil
+	case l.TimeZone != "":
+		if l.zone == nil || l.zone.name != l.TimeZone {
+			loc, err := time.LoadLocation(l.TimeZone)
+			if err != nil {
+				return nil, fmt.Errorf("can't load time zone: %s", err)
+			}
+			l.zone = &zone{name: l.TimeZone, loc: loc}
+		}
+		return l.zone.loc, nil
+	case l.LocalTime:
+		return time.Local, nil
+	}
+	return time.UTC, nil
+}
+
+// zone caches the time zone loaded for TimeZone.
+type zone struct {
+	name string
+	loc  *time.Location
+}
+
+// maxAge returns the maximum age of backups set by MaxAgeDuration or MaxAge,
+// or 0 if there is none.
+func (l *Logger) maxAge() time.Duration {
+	if l.MaxAgeDuration > 0 {
+		return l.MaxAgeDuration
+	}
+	return time.Duration(int64(24*time.Hour) * int64(l.MaxAge))
+}
@@ -0,0 +1,109 @@
+package logrotate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeZoneNaming(t *testing.T) {
+	currentTime = fakeTime
+	ny, err := time.LoadLocation("America/New_York")
+	isNil(err, t)
+	fsys := NewMemFS()
+	l := &Logger{
+		Filename:           logFile("/logs"),
+		FilenameTimeFormat: backupTimeFormat,
+		TimeZone:           "America/New_York",
+		FS:                 fsys,
+	}
+	defer l.Close()
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+
+	name := "foobar-" + fakeTime().In(ny).Format(backupTimeFormat) + ".log"
+	memExistsWithContent(fsys, "/logs/"+name, []byte("boo!"), t)
+
+	// the timestamp is read back in the same time zone.
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	assert(backups[0].Time.Equal(fakeTime().Truncate(time.Millisecond)), t,
+		"expected backup time %v, got %v", fakeTime(), backups[0].Time)
+
+	l.TimeZone = "Nowhere/Special"
+	notNil(l.Rotate(), t)
+	_, err = l.Backups()
+	notNil(err, t)
+}
+
+func TestReconfigureInvalidTimeZone(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	l := &Logger{
+		Filename:           logFile("/logs"),
+		FilenameTimeFormat: backupTimeFormat,
+		TimeZone:           "America/New_York",
+		FS:                 fsys,
+	}
+	defer l.Close()
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	cfg := l.Config()
+	cfg.TimeZone = "Nowhere/Special"
+	notNil(l.Reconfigure(cfg), t)
+	equals("America/New_York", l.TimeZone, t)
+	isNil(l.Rotate(), t)
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+}
+
+func TestMaxAgeDuration(t *testing.T) {
+	currentTime = fakeTime
+	fsys := NewMemFS()
+	isNil(fsys.MkdirAll("/logs", 0755), t)
+	for _, age := range []time.Duration{time.Hour, 3 * time.Hour} {
+		ts := fakeTime().Add(-age).UTC().Format(backupTimeFormat)
+		isNil(writeFile(fsys, "/logs/foobar-"+ts+".log", []byte("old"), 0644), t)
+	}
+
+	l := &Logger{
+		Filename:           logFile("/logs"),
+		FilenameTimeFormat: backupTimeFormat,
+		MaxAge:             1,
+		MaxAgeDuration:     2 * time.Hour,
+		FS:                 fsys,
+	}
+	defer l.Close()
+	plan, err := l.Plan()
+	isNil(err, t)
+	equals(1, len(plan.Remove), t)
+	equals("foobar-"+fakeTime().Add(-3*time.Hour).UTC().Format(backupTimeFormat)+".log", plan.Remove[0].Name, t)
+}
+
+func TestCalendarDays(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	isNil(err, t)
+	// daylight saving time started on March 10, 2024, a day of 23 hours.
+	now := time.Date(2024, 3, 11, 0, 30, 0, 0, ny)
+	backups := []BackupInfo{
+		{Name: "foo-2.log", Time: time.Date(2024, 3, 10, 0, 15, 0, 0, ny)},
+		{Name: "foo-1.log", Time: time.Date(2024, 3, 9, 23, 45, 0, 0, ny)},
+	}
+
+	// 24 hours ago was still March 9.
+	plan := LimitPolicy{MaxAge: 24 * time.Hour}.Plan(backups, now)
+	equals(2, len(plan.Keep), t)
+
+	// but March 9 is more than a calendar day ago.
+	plan = LimitPolicy{MaxAgeDays: 1, Location: ny}.Plan(backups, now)
+	equals(backups[:1], plan.Keep, t)
+	equals(backups[1:], plan.Remove, t)
+
+	l := &Logger{MaxAge: 1, CalendarDays: true, TimeZone: "America/New_York"}
+	p, err := l.retention()
+	isNil(err, t)
+	equals(LimitPolicy{MaxAge: 24 * time.Hour, MaxAgeDays: 1, Location: ny}, p, t)
+}
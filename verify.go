@@ -0,0 +1,168 @@
+package logrotate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// VerifyIssue is a problem found by Verify.
+type VerifyIssue struct {
+	// Name is the name of the backup file concerned.
+	Name string
+	// Problem describes what is wrong with it.
+	Problem string
+}
+
+func (i VerifyIssue) String() string {
+	return i.Name + ": " + i.Problem
+}
+
+// signedData returns the data of the entry covered by its signature. The
+// compression and encryption suffixes are left out of the name, so that
+// compressing or encrypting a backup keeps the signature valid.
+func (e *manifestEntry) signedData() []byte {
+	return []byte(fmt.Sprintf("%s\n%d\n%s\n%d\n%s\n%s\n%t",
+		baseBackupName(e.Name), e.Order,
+		e.RotatedAt.UTC().Format(time.RFC3339Nano), e.Size, e.SHA256, e.PrevSHA256, e.Removed))
+}
+
+// signedData returns the data of the head covered by its signature.
+func (h *manifestHead) signedData() []byte {
+	return []byte(fmt.Sprintf("%d\n%s", h.Count, h.SHA256))
+}
+
+// Verify checks the backups against the chain recorded in the manifest when
+// Checksum is set, and against the checksums of its entries otherwise. It
+// reports the backups that were modified, deleted other than by the cleanup
+// of old log files or that are not in the manifest, and the breaks in the
+// chain, including entries cut off its end. Signatures are checked too if a
+// VerifyKey or a SigningKey is set; without them, whoever can write the
+// manifest can make it match any change. An empty result means that the
+// backups are intact.
+func (l *Logger) Verify() ([]VerifyIssue, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m, err := l.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	key := l.VerifyKey
+	if key == nil && l.SigningKey != nil {
+		key = l.SigningKey.Public().(ed25519.PublicKey)
+	}
+
+	// A manifest kept without Checksum is neither chained nor signed, so
+	// only the backups themselves are checked against it.
+	chained := l.Checksum || m.Head != nil
+	for i := range m.Backups {
+		if m.Backups[i].PrevSHA256 != "" {
+			chained = true
+		}
+	}
+
+	var issues []VerifyIssue
+	manifestName := filepath.Base(l.manifestPath())
+	switch h := m.Head; {
+	case !chained:
+	case h == nil:
+		if len(m.Backups) > 0 {
+			issues = append(issues, VerifyIssue{manifestName, "head missing"})
+		}
+	default:
+		if want := m.head(); h.Count != want.Count || h.SHA256 != want.SHA256 {
+			issues = append(issues, VerifyIssue{manifestName, "head does not match the last entry"})
+		}
+		if key != nil {
+			sig, err := hex.DecodeString(h.Signature)
+			if err != nil || !ed25519.Verify(key, h.signedData(), sig) {
+				issues = append(issues, VerifyIssue{manifestName, "invalid head signature"})
+			}
+		}
+	}
+
+	// live holds the entries of the backups that were not removed, by base
+	// name.
+	live := make(map[string]*manifestEntry)
+	prev := ""
+	for i := range m.Backups {
+		e := &m.Backups[i]
+		if !chained {
+			live[baseBackupName(e.Name)] = e
+			continue
+		}
+		if e.PrevSHA256 != prev {
+			issues = append(issues, VerifyIssue{e.Name, "chain broken: previous checksum does not match"})
+		}
+		prev = e.SHA256
+		if key != nil {
+			sig, err := hex.DecodeString(e.Signature)
+			if err != nil || !ed25519.Verify(key, e.signedData(), sig) {
+				issues = append(issues, VerifyIssue{e.Name, "invalid signature"})
+			}
+		}
+		base := baseBackupName(e.Name)
+		if e.Removed {
+			if b := live[base]; b == nil || b.SHA256 != e.SHA256 {
+				issues = append(issues, VerifyIssue{e.Name, "removal of an unknown backup"})
+			}
+			delete(live, base)
+			continue
+		}
+		live[base] = e
+	}
+
+	for i := range m.Backups {
+		e := &m.Backups[i]
+		if live[baseBackupName(e.Name)] != e {
+			continue
+		}
+		sum, err := l.backupChecksum(filepath.Join(l.dir(), e.Name))
+		switch {
+		case os.IsNotExist(err):
+			issues = append(issues, VerifyIssue{e.Name, "missing"})
+		case err != nil:
+			issues = append(issues, VerifyIssue{e.Name, fmt.Sprintf("can't read: %s", err)})
+		case sum != e.SHA256:
+			issues = append(issues, VerifyIssue{e.Name, "checksum mismatch"})
+		}
+	}
+
+	if l.dirExists() {
+		files, err := l.oldLogFiles()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if m.find(f.Name()) == nil {
+				issues = append(issues, VerifyIssue{f.Name(), "not in manifest"})
+			}
+		}
+	}
+	return issues, nil
+}
+
+// backupChecksum returns the hex encoded SHA-256 checksum of the original
+// content of the backup at path.
+func (l *Logger) backupChecksum(path string) (string, error) {
+	f, err := open(l.fs(), path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	r, err := l.backupReader(f, filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
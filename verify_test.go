@@ -0,0 +1,185 @@
+package logrotate
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestVerifyChain(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestVerifyChain", t)
+	defer os.RemoveAll(dir)
+
+	_, key, err := ed25519.GenerateKey(nil)
+	isNil(err, t)
+	l := &Logger{
+		Filename:   logFile(dir),
+		Compress:   true,
+		MaxBackups: 2,
+		Checksum:   true,
+		SigningKey: key,
+	}
+	defer l.Close()
+	for _, s := range []string{"one!", "two!", "three!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+	}
+
+	// The first backup has been removed by the cleanup, which is recorded
+	// at the end of the chain.
+	notExist(backupFileWithOrder(dir, 1)+compressSuffix, t)
+	m, err := l.loadManifest()
+	isNil(err, t)
+	equals(4, len(m.Backups), t)
+	equals(false, m.Backups[0].Removed, t)
+	equals(true, m.Backups[3].Removed, t)
+	equals("foobar.log.1.gz", m.Backups[3].Name, t)
+	equals(m.Backups[0].SHA256, m.Backups[1].PrevSHA256, t)
+	equals(m.Backups[1].SHA256, m.Backups[2].PrevSHA256, t)
+	equals(m.Backups[2].SHA256, m.Backups[3].PrevSHA256, t)
+	equals(4, m.Head.Count, t)
+
+	issues, err := l.Verify()
+	isNil(err, t)
+	equals(0, len(issues), t)
+
+	// Verifying with the public key only.
+	auditor := &Logger{
+		Filename:  logFile(dir),
+		VerifyKey: key.Public().(ed25519.PublicKey),
+	}
+	issues, err = auditor.Verify()
+	isNil(err, t)
+	equals(0, len(issues), t)
+}
+
+func TestVerifyTampering(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestVerifyTampering", t)
+	defer os.RemoveAll(dir)
+
+	_, key, err := ed25519.GenerateKey(nil)
+	isNil(err, t)
+	l := &Logger{
+		Filename:   logFile(dir),
+		Checksum:   true,
+		SigningKey: key,
+	}
+	defer l.Close()
+	for _, s := range []string{"one!", "two!", "three!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+	}
+
+	isNil(os.WriteFile(backupFileWithOrder(dir, 1), []byte("ONE!"), 0644), t)
+	isNil(os.Remove(backupFileWithOrder(dir, 2)), t)
+	isNil(os.WriteFile(backupFileWithOrder(dir, 9), []byte("nine!"), 0644), t)
+	tamperManifest(l, func(m *manifest) {
+		m.Backups[2].RotatedAt = m.Backups[2].RotatedAt.Add(time.Hour)
+	}, t)
+
+	issues, err := l.Verify()
+	isNil(err, t)
+	equals([]VerifyIssue{
+		{"foobar.log.manifest.json", "head does not match the last entry"},
+		{"foobar.log.3", "invalid signature"},
+		{"foobar.log.1", "checksum mismatch"},
+		{"foobar.log.2", "missing"},
+		{"foobar.log.9", "not in manifest"},
+	}, issues, t)
+}
+
+func TestVerifyRemovalTampering(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestVerifyRemovalTampering", t)
+	defer os.RemoveAll(dir)
+
+	_, key, err := ed25519.GenerateKey(nil)
+	isNil(err, t)
+	l := &Logger{
+		Filename:   logFile(dir),
+		Checksum:   true,
+		SigningKey: key,
+	}
+	defer l.Close()
+	for _, s := range []string{"one!", "two!", "three!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+	}
+
+	// A backup deleted by hand and flagged as removed in its entry.
+	isNil(os.Remove(backupFileWithOrder(dir, 2)), t)
+	tamperManifest(l, func(m *manifest) {
+		m.Backups[1].Removed = true
+	}, t)
+	issues, err := l.Verify()
+	isNil(err, t)
+	equals([]VerifyIssue{
+		{"foobar.log.2", "invalid signature"},
+		{"foobar.log.2", "removal of an unknown backup"},
+	}, issues, t)
+
+	// The last backup deleted by hand along with its entry.
+	tamperManifest(l, func(m *manifest) {
+		m.Backups[1].Removed = false
+	}, t)
+	isNil(os.WriteFile(backupFileWithOrder(dir, 2), []byte("two!"), 0644), t)
+	isNil(os.Remove(backupFileWithOrder(dir, 3)), t)
+	tamperManifest(l, func(m *manifest) {
+		m.Backups = m.Backups[:2]
+	}, t)
+	issues, err = l.Verify()
+	isNil(err, t)
+	equals([]VerifyIssue{
+		{"foobar.log.manifest.json", "head does not match the last entry"},
+	}, issues, t)
+}
+
+// tamperManifest applies fn to the manifest of l and writes it back as is,
+// without updating its head.
+func tamperManifest(l *Logger, fn func(*manifest), t testing.TB) {
+	m, err := l.loadManifest()
+	isNilUp(err, t, 1)
+	fn(m)
+	data, err := json.Marshal(m)
+	isNilUp(err, t, 1)
+	isNilUp(os.WriteFile(l.manifestPath(), data, 0600), t, 1)
+}
+
+func TestVerifyWithoutChecksum(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestVerifyWithoutChecksum", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Manifest: true,
+	}
+	defer l.Close()
+	for _, s := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+	}
+
+	// the entries are not chained, so only the backups are checked.
+	issues, err := l.Verify()
+	isNil(err, t)
+	equals(0, len(issues), t)
+
+	isNil(os.Remove(backupFileWithOrder(dir, 1)), t)
+	isNil(os.WriteFile(backupFileWithOrder(dir, 2), []byte("six!"), 0644), t)
+	issues, err = l.Verify()
+	isNil(err, t)
+	equals([]VerifyIssue{
+		{"foobar.log.1", "missing"},
+		{"foobar.log.2", "checksum mismatch"},
+	}, issues, t)
+}